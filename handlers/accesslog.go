@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+// RedactedValue replaces any redacted path segment or query parameter value in the access log
+const RedactedValue = "REDACTED"
+
+// DefaultRedactedQueryParams are the query parameters redacted when AccessLogConfig.RedactQueryParams is nil
+var DefaultRedactedQueryParams = []string{"token", "access_token", "api_key", "key", "password"}
+
+// PathRedaction replaces any part of a request path matching Pattern with Replacement
+// (RedactedValue if empty) before it is logged. Replacement may reference capture groups.
+type PathRedaction struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// AccessLogConfig defines the behaviour of the AccessLog middleware.
+//
+// Router is used to resolve the route template of each request. If it is nil the route
+// template is only available when the middleware is registered on a mux.Router with Use.
+//
+// Requests to any of SampledPaths are only logged at SampleRate (between 0 and 1), unless
+// they return an error status. This is intended to reduce the noise from health checks.
+type AccessLogConfig struct {
+	Router            *mux.Router
+	RedactQueryParams []string
+	RedactPaths       []PathRedaction
+	SampledPaths      []string
+	SampleRate        float64
+}
+
+// AccessLog is a middleware that logs a single event for each completed request with the
+// method, route template, status code, bytes written, latency, request ID, caller identity and
+// collection ID. It is intended to replace the default log middleware on a Server:
+//
+//	httpServer.AddMiddleware(dphttp.LogHandlerKey, handlers.AccessLog(cfg))
+//
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func AccessLog(cfg AccessLogConfig) func(http.Handler) http.Handler {
+	redactedParams := cfg.RedactQueryParams
+	if redactedParams == nil {
+		redactedParams = DefaultRedactedQueryParams
+	}
+	sampledPaths := make(map[string]struct{}, len(cfg.SampledPaths))
+	for _, p := range cfg.SampledPaths {
+		sampledPaths[p] = struct{}{}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now().UTC()
+			routeTemplate := getRouteTemplate(cfg.Router, req)
+			rc := &responseCapture{ResponseWriter: w}
+
+			defer func() {
+				end := time.Now().UTC()
+				status := rc.Status()
+				if status == 0 {
+					// nothing was written by the handler, so net/http sends a 200 OK
+					status = http.StatusOK
+				}
+
+				if _, ok := sampledPaths[req.URL.Path]; ok && status < http.StatusBadRequest && !sample(cfg.SampleRate) {
+					return
+				}
+
+				ctx := req.Context()
+				logData := log.Data{
+					"latency_ms": end.Sub(start).Milliseconds(),
+				}
+				if routeTemplate != "" {
+					logData["route"] = routeTemplate
+				}
+				if requestID := request.GetRequestId(ctx); requestID != "" {
+					logData["request_id"] = requestID
+				}
+				if caller := request.Caller(ctx); caller != "" {
+					logData["caller_identity"] = caller
+				}
+				if collectionID, err := request.GetCollectionID(req); err == nil && collectionID != "" {
+					logData["collection_id"] = collectionID
+				}
+
+				event := log.HTTP(req, status, rc.bytesWritten, &start, &end)
+				if e, ok := event.(*log.EventHTTP); ok {
+					e.Path = redactPath(req.URL.Path, cfg.RedactPaths)
+					e.Query = redactQuery(req.URL.RawQuery, redactedParams)
+				}
+
+				log.Info(ctx, "http request completed", event, logData)
+			}()
+
+			h.ServeHTTP(rc, req)
+		})
+	}
+}
+
+// getRouteTemplate returns the path template of the route matching the request, if any
+func getRouteTemplate(router *mux.Router, req *http.Request) string {
+	var route *mux.Route
+	if router != nil {
+		var match mux.RouteMatch
+		if router.Match(req, &match) {
+			route = match.Route
+		}
+	} else {
+		route = mux.CurrentRoute(req)
+	}
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return tpl
+}
+
+func redactPath(path string, redactions []PathRedaction) string {
+	for _, r := range redactions {
+		if r.Pattern == nil {
+			continue
+		}
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = RedactedValue
+		}
+		path = r.Pattern.ReplaceAllString(path, replacement)
+	}
+	return path
+}
+
+func redactQuery(rawQuery string, params []string) string {
+	if rawQuery == "" || len(params) == 0 {
+		return rawQuery
+	}
+	query, err := neturl.ParseQuery(rawQuery)
+	if err != nil {
+		return RedactedValue
+	}
+	redacted := false
+	for key, values := range query {
+		for _, p := range params {
+			if strings.EqualFold(key, p) {
+				for i := range values {
+					values[i] = RedactedValue
+				}
+				redacted = true
+			}
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return query.Encode()
+}
+
+//nolint:gosec // math/rand is used for log sampling, not for security purposes
+var sampleRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+var sampleMutex sync.Mutex
+
+// sample returns true with the given probability
+func sample(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	sampleMutex.Lock()
+	defer sampleMutex.Unlock()
+	return sampleRandom.Float64() < rate
+}
+
+// responseCapture wraps a http.ResponseWriter to record the status code and number of bytes written
+type responseCapture struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+}
+
+// Status returns the status code written to the response, which is 200 if the handler
+// wrote a body without calling WriteHeader, or 0 if nothing was written
+func (r *responseCapture) Status() int {
+	return r.statusCode
+}
+
+func (r *responseCapture) WriteHeader(status int) {
+	if r.statusCode == 0 {
+		r.statusCode = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseCapture) Write(b []byte) (n int, err error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	n, err = r.ResponseWriter.Write(b)
+	r.bytesWritten += int64(n)
+	return
+}
+
+func (r *responseCapture) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("handlers: response does not implement http.Hijacker")
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use by http.ResponseController
+func (r *responseCapture) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
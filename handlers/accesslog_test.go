@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	dprequest "github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessLog(t *testing.T) {
+	Convey("Given an access log middleware wrapping a router", t, func() {
+		buf := &bytes.Buffer{}
+		log.SetDestination(buf, nil)
+		defer log.SetDestination(os.Stdout, nil)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/datasets/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+		})
+		router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+		router.HandleFunc("/users/{id}/token/{token}", func(w http.ResponseWriter, r *http.Request) {})
+
+		cfg := AccessLogConfig{
+			Router: router,
+			RedactPaths: []PathRedaction{
+				{Pattern: regexp.MustCompile(`/token/[^/]+`), Replacement: "/token/" + RedactedValue},
+			},
+			SampledPaths: []string{"/health"},
+			SampleRate:   0,
+		}
+		target := AccessLog(cfg)(router)
+
+		Convey("When a request is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080/datasets/cpih?token=secret&limit=10", http.NoBody)
+			r.Header.Set(dprequest.CollectionIDHeaderKey, "my-collection")
+			ctx := dprequest.WithRequestId(r.Context(), "req-123")
+			ctx = context.WithValue(ctx, dprequest.CallerIdentityKey, testUserIdentifier)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r.WithContext(ctx))
+
+			Convey("Then the response is passed through", func() {
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(w.Body.String(), ShouldEqual, "hello")
+			})
+
+			Convey("And the request is logged with its details", func() {
+				logged := buf.String()
+				So(logged, ShouldContainSubstring, `"event":"http request completed"`)
+				So(logged, ShouldContainSubstring, `"route":"/datasets/{id}"`)
+				So(logged, ShouldContainSubstring, `"status_code":201`)
+				So(logged, ShouldContainSubstring, `"response_content_length":5`)
+				So(logged, ShouldContainSubstring, `"request_id":"req-123"`)
+				So(logged, ShouldContainSubstring, `"caller_identity":"fred@ons.gov.uk"`)
+				So(logged, ShouldContainSubstring, `"collection_id":"my-collection"`)
+			})
+
+			Convey("And sensitive query parameters are redacted", func() {
+				So(buf.String(), ShouldContainSubstring, `"query":"limit=10\u0026token=REDACTED"`)
+				So(buf.String(), ShouldNotContainSubstring, "secret")
+			})
+		})
+
+		Convey("When a request is made to a path containing a secret", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/users/1/token/secret", http.NoBody)
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the path is redacted", func() {
+				So(buf.String(), ShouldContainSubstring, `"path":"/users/1/token/REDACTED"`)
+				So(buf.String(), ShouldNotContainSubstring, "secret")
+			})
+
+			Convey("And the status sent by net/http is logged, as the handler wrote nothing", func() {
+				So(buf.String(), ShouldContainSubstring, `"status_code":200`)
+			})
+		})
+
+		Convey("When a request is made to a sampled path", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/health", http.NoBody)
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the request is not logged", func() {
+				So(buf.String(), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestRedactQuery(t *testing.T) {
+	Convey("Given a query without sensitive parameters", t, func() {
+		query := "b=2&a=1"
+
+		Convey("Then the query is returned unchanged", func() {
+			So(redactQuery(query, DefaultRedactedQueryParams), ShouldEqual, query)
+		})
+	})
+
+	Convey("Given a query with a sensitive parameter in a different case", t, func() {
+		query := "ACCESS_TOKEN=abc"
+
+		Convey("Then the value is redacted", func() {
+			So(redactQuery(query, DefaultRedactedQueryParams), ShouldEqual, "ACCESS_TOKEN=REDACTED")
+		})
+	})
+}
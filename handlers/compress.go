@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encoding header constants and supported encodings
+const (
+	AcceptEncodingHeader  = "Accept-Encoding"
+	ContentEncodingHeader = "Content-Encoding"
+	ContentLengthHeader   = "Content-Length"
+	ContentTypeHeader     = "Content-Type"
+	ETagHeader            = "ETag"
+	IfMatchHeader         = "If-Match"
+	IfNoneMatchHeader     = "If-None-Match"
+
+	EncodingBrotli   = "br"
+	EncodingZstd     = "zstd"
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+// Default values used when the corresponding CompressionConfig field is empty
+var (
+	DefaultCompressionEncodings    = []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+	DefaultCompressionMinSize      = 1024
+	DefaultCompressionContentTypes = []string{
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"image/svg+xml",
+		"text/*",
+	}
+)
+
+// CompressionConfig defines the behaviour of the Compress middleware.
+//
+// Encodings lists the supported encodings in order of server preference, which is used to
+// break ties between encodings the client accepts with the same q-value. Responses are only
+// compressed if they are at least MinSize bytes long and their content type matches one of
+// ContentTypes, where a trailing "/*" matches any subtype.
+type CompressionConfig struct {
+	Encodings    []string
+	MinSize      int
+	ContentTypes []string
+}
+
+// Compress is a middleware that compresses responses using the best encoding accepted by the
+// client, as negotiated from the Accept-Encoding header. Compressed responses have the encoding
+// appended to any strong ETag, e.g. "abc" becomes "abc-gzip", as the compressed representation
+// is not byte-for-byte identical to the one the ETag was generated from. The suffix is removed
+// from the ETags in the If-Match and If-None-Match headers of requests before they reach the
+// handler, so that handlers compare them against the ETags they generated.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func Compress(cfg CompressionConfig) func(http.Handler) http.Handler {
+	encodings := cfg.Encodings
+	if len(encodings) == 0 {
+		encodings = DefaultCompressionEncodings
+	}
+	for _, e := range encodings {
+		if _, ok := encoderPools[e]; !ok {
+			panic("unsupported compression encoding: " + e)
+		}
+	}
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+	contentTypes := cfg.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultCompressionContentTypes
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			encoding := NegotiateEncoding(req.Header.Get(AcceptEncodingHeader), encodings)
+			req, suffixed := stripETagSuffixes(req, encodings)
+			cw := &compressWriter{
+				ResponseWriter: w,
+				req:            req,
+				encoding:       encoding,
+				minSize:        minSize,
+				contentTypes:   contentTypes,
+				suffixETags:    encoding != "" && suffixed[encoding],
+			}
+			defer func() {
+				if err := cw.Close(); err != nil {
+					log.Error(req.Context(), "failed to close compressed response", err, log.Data{"encoding": encoding})
+				}
+			}()
+
+			h.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// NegotiateEncoding returns the encoding from supported that is preferred by the provided
+// Accept-Encoding header value, or an empty string if the response should not be encoded.
+// Encodings are ranked by q-value, with ties broken by their order in supported.
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qValues := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := request.ParseQValue(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		qValues[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qValues[enc]
+		if !ok {
+			if wildcard < 0 {
+				continue
+			}
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// encoder is implemented by the writers of each supported encoding
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}},
+	EncodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	EncodingZstd: {New: func() interface{} {
+		//nolint:errcheck // NewWriter only returns an error for invalid options
+		enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+}
+
+// compressWriter buffers the start of a response until it can determine whether the response
+// should be compressed, then either passes it through or writes it through an encoder
+type compressWriter struct {
+	http.ResponseWriter
+	req          *http.Request
+	encoding     string
+	minSize      int
+	contentTypes []string
+	// suffixETags is set if the request's conditional headers had ETags with the suffix of the
+	// encoding, so the ETag of a 304 Not Modified response must have the suffix too
+	suffixETags bool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+	closed  bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 || cw.decided {
+		return
+	}
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if !cw.mayCompress(true) {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+		cw.start(cw.isCompressible())
+		buf := cw.buf
+		cw.buf = nil
+		if _, err := cw.writeBody(buf); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return cw.writeBody(b)
+}
+
+func (cw *compressWriter) writeBody(b []byte) (int, error) {
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush writes any buffered data to the client. A response that has not been decided yet is
+// compressed if it is eligible, regardless of its size, as the final size cannot be known.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if !cw.decided {
+			cw.start(cw.isCompressible())
+			buf := cw.buf
+			cw.buf = nil
+			if _, err := cw.writeBody(buf); err != nil {
+				return
+			}
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		cw.decided = true
+		cw.closed = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("handlers: response does not implement http.Hijacker")
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use by http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close writes any remaining buffered data and releases the encoder
+func (cw *compressWriter) Close() error {
+	if cw.closed {
+		return nil
+	}
+	cw.closed = true
+
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// nothing was written by the handler, so leave the default response untouched
+			return nil
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(len(cw.buf) >= cw.minSize && cw.isCompressible())
+		if _, err := cw.writeBody(cw.buf); err != nil {
+			return err
+		}
+		cw.buf = nil
+	}
+
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// mayCompress reports whether the response could be compressed, based on the request and
+// the headers and status written so far. If allowUnknownType is true, a response without a
+// content type is considered compressible as its content type may be sniffed from the body.
+func (cw *compressWriter) mayCompress(allowUnknownType bool) bool {
+	if cw.encoding == "" || cw.req.Method == http.MethodHead {
+		return false
+	}
+	if cw.status < http.StatusOK || cw.status == http.StatusNoContent ||
+		cw.status == http.StatusNotModified || cw.status == http.StatusPartialContent {
+		return false
+	}
+	header := cw.Header()
+	if header.Get(ContentEncodingHeader) != "" {
+		return false
+	}
+	contentType := header.Get(ContentTypeHeader)
+	if !(allowUnknownType && contentType == "") && !cw.isAllowedContentType(contentType) {
+		return false
+	}
+	if cl := header.Get(ContentLengthHeader); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < cw.minSize {
+			return false
+		}
+	}
+	return true
+}
+
+// isCompressible is mayCompress with a content type sniffed from the buffered body, if the
+// handler did not set one
+func (cw *compressWriter) isCompressible() bool {
+	header := cw.Header()
+	if header.Get(ContentTypeHeader) == "" && len(cw.buf) > 0 {
+		header.Set(ContentTypeHeader, http.DetectContentType(cw.buf))
+	}
+	return cw.mayCompress(false)
+}
+
+func (cw *compressWriter) isAllowedContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range cw.contentTypes {
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// start writes the response headers, setting up the encoder if the response is to be compressed
+func (cw *compressWriter) start(compress bool) {
+	cw.decided = true
+	header := cw.Header()
+
+	// the response varies by encoding whether or not this request accepted one, so that a cache
+	// does not serve an uncompressed response to clients that accept compression, or vice versa
+	if cw.isAllowedContentType(header.Get(ContentTypeHeader)) {
+		addVary(header, AcceptEncodingHeader)
+	}
+
+	if compress {
+		header.Set(ContentEncodingHeader, cw.encoding)
+		header.Del(ContentLengthHeader)
+		suffixETags(header, cw.encoding)
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	} else if cw.status == http.StatusNotModified && cw.suffixETags {
+		addVary(header, AcceptEncodingHeader)
+		suffixETags(header, cw.encoding)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// addVary adds value to the Vary header, unless it is already present
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values(VaryHeader) {
+		for _, existing := range strings.Split(v, ",") {
+			existing = strings.TrimSpace(existing)
+			if existing == "*" || strings.EqualFold(existing, value) {
+				return
+			}
+		}
+	}
+	header.Add(VaryHeader, value)
+}
+
+// suffixETags appends "-<encoding>" to any strong ETags in the header. Weak ETags are left
+// unchanged, as they only identify semantically equivalent representations.
+func suffixETags(header http.Header, encoding string) {
+	etags := header.Values(ETagHeader)
+	header.Del(ETagHeader)
+	for _, etag := range etags {
+		if len(etag) >= 2 && strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`) {
+			etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+		}
+		header.Add(ETagHeader, etag)
+	}
+}
+
+// stripETagSuffixes returns the request with the encoding suffixes added by suffixETags removed
+// from the ETags of its If-Match and If-None-Match headers, and the encodings that were removed
+func stripETagSuffixes(req *http.Request, encodings []string) (*http.Request, map[string]bool) {
+	stripped := map[string]bool{}
+	var header http.Header
+	for _, name := range []string{IfMatchHeader, IfNoneMatchHeader} {
+		values := slices.Clone(req.Header.Values(name))
+		changed := false
+		for i, value := range values {
+			if v := stripETagListSuffixes(value, encodings, stripped); v != value {
+				values[i], changed = v, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if header == nil {
+			header = req.Header.Clone()
+		}
+		header[http.CanonicalHeaderKey(name)] = values
+	}
+	if header == nil {
+		return req, stripped
+	}
+	req = req.Clone(req.Context())
+	req.Header = header
+	return req, stripped
+}
+
+// stripETagListSuffixes removes the encoding suffixes from each entity tag in a list, e.g.
+// `W/"abc-gzip", "def"` becomes `W/"abc", "def"`, recording each encoding that was removed
+func stripETagListSuffixes(list string, encodings []string, stripped map[string]bool) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(list, '"')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(list[start+1:], '"')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		opaque := list[start+1 : end]
+		for _, encoding := range encodings {
+			if trimmed, found := strings.CutSuffix(opaque, "-"+encoding); found {
+				opaque = trimmed
+				stripped[encoding] = true
+				break
+			}
+		}
+		b.WriteString(list[:start+1])
+		b.WriteString(opaque)
+		b.WriteByte('"')
+		list = list[end+1:]
+	}
+	b.WriteString(list)
+	return b.String()
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/handlers/response"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	Convey("Given the default supported encodings", t, func() {
+		supported := DefaultCompressionEncodings
+
+		Convey("Then the highest q-value supported encoding is chosen", func() {
+			So(NegotiateEncoding("gzip;q=0.8, zstd;q=0.9, br;q=0.1", supported), ShouldEqual, EncodingZstd)
+		})
+
+		Convey("Then ties are broken by server preference", func() {
+			So(NegotiateEncoding("gzip, deflate, br", supported), ShouldEqual, EncodingBrotli)
+		})
+
+		Convey("Then encodings with a q-value of 0 are never chosen", func() {
+			So(NegotiateEncoding("br;q=0, zstd;q=0, gzip", supported), ShouldEqual, EncodingGzip)
+			So(NegotiateEncoding("gzip;q=0", supported), ShouldBeEmpty)
+		})
+
+		Convey("Then a wildcard applies to encodings that are not listed", func() {
+			So(NegotiateEncoding("br;q=0, *;q=0.5", supported), ShouldEqual, EncodingZstd)
+		})
+
+		Convey("Then no encoding is chosen if none are acceptable", func() {
+			So(NegotiateEncoding("", supported), ShouldBeEmpty)
+			So(NegotiateEncoding("identity", supported), ShouldBeEmpty)
+			So(NegotiateEncoding("gzip;q=invalid", supported), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestCompress(t *testing.T) {
+	largeBody := `{"items":"` + strings.Repeat("a", 2048) + `"}`
+	etag := response.GenerateETag([]byte(largeBody), false)
+
+	jsonHandler := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(ContentTypeHeader, "application/json; charset=utf-8")
+			response.SetETag(w, etag)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		})
+	}
+
+	decoders := map[string]func(io.Reader) io.Reader{
+		EncodingGzip: func(r io.Reader) io.Reader {
+			gr, err := gzip.NewReader(r)
+			So(err, ShouldBeNil)
+			return gr
+		},
+		EncodingBrotli: func(r io.Reader) io.Reader {
+			return brotli.NewReader(r)
+		},
+		EncodingZstd: func(r io.Reader) io.Reader {
+			zr, err := zstd.NewReader(r)
+			So(err, ShouldBeNil)
+			return zr
+		},
+	}
+
+	for encoding, decode := range decoders {
+		Convey("Given a large JSON response and a client accepting "+encoding, t, func() {
+			target := Compress(CompressionConfig{})(jsonHandler(largeBody))
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(AcceptEncodingHeader, encoding)
+			w := httptest.NewRecorder()
+
+			Convey("When the request is served", func() {
+				target.ServeHTTP(w, r)
+
+				Convey("Then the response is compressed", func() {
+					So(w.Code, ShouldEqual, http.StatusOK)
+					So(w.Header().Get(ContentEncodingHeader), ShouldEqual, encoding)
+					So(w.Header().Get(VaryHeader), ShouldEqual, AcceptEncodingHeader)
+
+					b, err := io.ReadAll(decode(bytes.NewReader(w.Body.Bytes())))
+					So(err, ShouldBeNil)
+					So(string(b), ShouldEqual, largeBody)
+				})
+
+				Convey("And the encoding is appended to the strong ETag", func() {
+					So(w.Header().Get(ETagHeader), ShouldEqual, strings.TrimSuffix(etag, `"`)+"-"+encoding+`"`)
+				})
+			})
+		})
+	}
+
+	Convey("Given a response smaller than the minimum size", t, func() {
+		body := `{"items":[]}`
+		target := Compress(CompressionConfig{})(jsonHandler(body))
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+		r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+		w := httptest.NewRecorder()
+
+		Convey("When the request is served", func() {
+			target.ServeHTTP(w, r)
+
+			Convey("Then the response is not compressed but still varies by encoding", func() {
+				So(w.Header().Get(ContentEncodingHeader), ShouldBeEmpty)
+				So(w.Header().Get(VaryHeader), ShouldEqual, AcceptEncodingHeader)
+				So(w.Header().Get(ETagHeader), ShouldEqual, etag)
+				So(w.Body.String(), ShouldEqual, body)
+			})
+		})
+	})
+
+	Convey("Given a large JSON response and a client that does not accept compression", t, func() {
+		target := Compress(CompressionConfig{})(jsonHandler(largeBody))
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+		w := httptest.NewRecorder()
+
+		Convey("When the request is served", func() {
+			target.ServeHTTP(w, r)
+
+			Convey("Then the response is not compressed but still varies by encoding", func() {
+				So(w.Header().Get(ContentEncodingHeader), ShouldBeEmpty)
+				So(w.Header().Get(VaryHeader), ShouldEqual, AcceptEncodingHeader)
+				So(w.Header().Get(ETagHeader), ShouldEqual, etag)
+				So(w.Body.String(), ShouldEqual, largeBody)
+			})
+		})
+	})
+
+	Convey("Given a handler that supports conditional requests", t, func() {
+		var ifNoneMatch, ifMatch string
+		target := Compress(CompressionConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch, ifMatch = r.Header.Get(IfNoneMatchHeader), r.Header.Get(IfMatchHeader)
+			w.Header().Set(ContentTypeHeader, "application/json")
+			response.SetETag(w, etag)
+			if ifNoneMatch == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			if ifMatch != "" && ifMatch != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.Write([]byte(largeBody))
+		}))
+
+		Convey("When a compressed response is fetched", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+			compressedETag := w.Header().Get(ETagHeader)
+			So(compressedETag, ShouldNotEqual, etag)
+
+			Convey("Then a conditional GET with its ETag is not modified, and keeps the compressed ETag", func() {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+				r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+				r.Header.Set(IfNoneMatchHeader, compressedETag)
+				w := httptest.NewRecorder()
+				target.ServeHTTP(w, r)
+
+				So(ifNoneMatch, ShouldEqual, etag)
+				So(r.Header.Get(IfNoneMatchHeader), ShouldEqual, compressedETag)
+				So(w.Code, ShouldEqual, http.StatusNotModified)
+				So(w.Header().Get(ETagHeader), ShouldEqual, compressedETag)
+			})
+
+			Convey("Then an update with its ETag in If-Match reaches the handler without the suffix", func() {
+				r := httptest.NewRequest(http.MethodPut, "http://localhost:8080", http.NoBody)
+				r.Header.Set(IfMatchHeader, compressedETag)
+				w := httptest.NewRecorder()
+				target.ServeHTTP(w, r)
+
+				So(ifMatch, ShouldEqual, etag)
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When a list of ETags is given, then the suffix is removed from each of them", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(IfNoneMatchHeader, `W/"abc-br", "d,ef-zstd", "ghi"`)
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			So(ifNoneMatch, ShouldEqual, `W/"abc", "d,ef", "ghi"`)
+		})
+	})
+
+	Convey("Given a large response with a content type that is not allowed", t, func() {
+		body := strings.Repeat("a", 2048)
+		target := Compress(CompressionConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(ContentTypeHeader, "image/png")
+			w.Write([]byte(body))
+		}))
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+		r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+		w := httptest.NewRecorder()
+
+		Convey("When the request is served", func() {
+			target.ServeHTTP(w, r)
+
+			Convey("Then the response is not compressed and does not vary by encoding", func() {
+				So(w.Header().Get(ContentEncodingHeader), ShouldBeEmpty)
+				So(w.Header().Get(VaryHeader), ShouldBeEmpty)
+				So(w.Body.String(), ShouldEqual, body)
+			})
+		})
+	})
+
+	Convey("Given a large response written in chunks without a content type", t, func() {
+		body := "<html><body>" + strings.Repeat("a", 2048) + "</body></html>"
+		target := Compress(CompressionConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body[:10]))
+			w.Write([]byte(body[10:]))
+		}))
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+		r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+		w := httptest.NewRecorder()
+
+		Convey("When the request is served", func() {
+			target.ServeHTTP(w, r)
+
+			Convey("Then the content type is sniffed and the response is compressed", func() {
+				So(w.Header().Get(ContentTypeHeader), ShouldStartWith, "text/html")
+				So(w.Header().Get(ContentEncodingHeader), ShouldEqual, EncodingGzip)
+
+				gr, err := gzip.NewReader(w.Body)
+				So(err, ShouldBeNil)
+				b, err := io.ReadAll(gr)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, body)
+			})
+		})
+	})
+
+	Convey("Given a not modified response", t, func() {
+		target := Compress(CompressionConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(ContentTypeHeader, "application/json")
+			response.SetETag(w, etag)
+			w.WriteHeader(http.StatusNotModified)
+		}))
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+		r.Header.Set(AcceptEncodingHeader, EncodingGzip)
+		w := httptest.NewRecorder()
+
+		Convey("When the request is served", func() {
+			target.ServeHTTP(w, r)
+
+			Convey("Then the status and ETag are passed through unchanged", func() {
+				So(w.Code, ShouldEqual, http.StatusNotModified)
+				So(w.Header().Get(ContentEncodingHeader), ShouldBeEmpty)
+				So(w.Header().Get(ETagHeader), ShouldEqual, etag)
+			})
+		})
+	})
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS header constants
+const (
+	OriginHeader                        = "Origin"
+	VaryHeader                          = "Vary"
+	AccessControlAllowOriginHeader      = "Access-Control-Allow-Origin"
+	AccessControlAllowMethodsHeader     = "Access-Control-Allow-Methods"
+	AccessControlAllowHeadersHeader     = "Access-Control-Allow-Headers"
+	AccessControlAllowCredentialsHeader = "Access-Control-Allow-Credentials"
+	AccessControlExposeHeadersHeader    = "Access-Control-Expose-Headers"
+	AccessControlMaxAgeHeader           = "Access-Control-Max-Age"
+	AccessControlRequestMethodHeader    = "Access-Control-Request-Method"
+	AccessControlRequestHeadersHeader   = "Access-Control-Request-Headers"
+)
+
+// Default values used when the corresponding CORSConfig field is empty
+var (
+	DefaultCORSAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	DefaultCORSAllowedHeaders = []string{"Accept", "Content-Type"}
+)
+
+// CORSConfig defines the cross-origin resource sharing policy applied by the CORS middleware.
+//
+// AllowedOrigins may contain exact origins (e.g. "https://www.ons.gov.uk"), wildcard
+// subdomains (e.g. "*.ons.gov.uk", which matches any subdomain but not the apex domain)
+// or "*" to allow any origin. AllowedHeaders may contain "*" to allow any requested header.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type cors struct {
+	allowAllOrigins  bool
+	origins          map[string]struct{}
+	wildcardSuffixes []string
+	methods          []string
+	headers          []string
+	allowAllHeaders  bool
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// CORS is a middleware that applies the provided cross-origin policy to requests. Preflight
+// requests are answered directly with a 204 and are not passed to the wrapped handler.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	c := newCORS(cfg)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if isPreflight(req) {
+				c.handlePreflight(w, req)
+				return
+			}
+			c.handleActual(w, req)
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+func newCORS(cfg CORSConfig) *cors {
+	c := &cors{
+		origins:          map[string]struct{}{},
+		headers:          cfg.AllowedHeaders,
+		exposedHeaders:   strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			c.allowAllOrigins = true
+		case strings.HasPrefix(origin, "*."):
+			c.wildcardSuffixes = append(c.wildcardSuffixes, origin[1:])
+		case origin != "":
+			c.origins[origin] = struct{}{}
+		}
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSAllowedMethods
+	}
+	for _, method := range methods {
+		c.methods = append(c.methods, strings.ToUpper(method))
+	}
+
+	if len(c.headers) == 0 {
+		c.headers = DefaultCORSAllowedHeaders
+	}
+	for _, header := range c.headers {
+		if header == "*" {
+			c.allowAllHeaders = true
+		}
+	}
+
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	return c
+}
+
+// isPreflight determines whether the request is a CORS preflight request
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions &&
+		req.Header.Get(OriginHeader) != "" &&
+		req.Header.Get(AccessControlRequestMethodHeader) != ""
+}
+
+func (c *cors) handlePreflight(w http.ResponseWriter, req *http.Request) {
+	header := w.Header()
+	header.Add(VaryHeader, OriginHeader)
+	header.Add(VaryHeader, AccessControlRequestMethodHeader)
+	header.Add(VaryHeader, AccessControlRequestHeadersHeader)
+	defer w.WriteHeader(http.StatusNoContent)
+
+	origin := req.Header.Get(OriginHeader)
+	if !c.isOriginAllowed(origin) {
+		return
+	}
+
+	method := strings.ToUpper(req.Header.Get(AccessControlRequestMethodHeader))
+	if !c.isMethodAllowed(method) {
+		return
+	}
+
+	requestedHeaders := parseHeaderList(req.Header.Get(AccessControlRequestHeadersHeader))
+	if !c.areHeadersAllowed(requestedHeaders) {
+		return
+	}
+
+	c.setAllowOrigin(header, origin)
+	header.Set(AccessControlAllowMethodsHeader, method)
+	if len(requestedHeaders) > 0 {
+		header.Set(AccessControlAllowHeadersHeader, strings.Join(requestedHeaders, ", "))
+	}
+	if c.maxAge != "" {
+		header.Set(AccessControlMaxAgeHeader, c.maxAge)
+	}
+}
+
+func (c *cors) handleActual(w http.ResponseWriter, req *http.Request) {
+	header := w.Header()
+	header.Add(VaryHeader, OriginHeader)
+
+	origin := req.Header.Get(OriginHeader)
+	if origin == "" || !c.isOriginAllowed(origin) {
+		return
+	}
+
+	c.setAllowOrigin(header, origin)
+	if c.exposedHeaders != "" {
+		header.Set(AccessControlExposeHeadersHeader, c.exposedHeaders)
+	}
+}
+
+// setAllowOrigin sets the allowed origin and credentials headers. The request origin is echoed back
+// unless any origin is allowed without credentials, in which case "*" is sufficient.
+func (c *cors) setAllowOrigin(header http.Header, origin string) {
+	if c.allowAllOrigins && !c.allowCredentials {
+		header.Set(AccessControlAllowOriginHeader, "*")
+		return
+	}
+	header.Set(AccessControlAllowOriginHeader, origin)
+	if c.allowCredentials {
+		header.Set(AccessControlAllowCredentialsHeader, "true")
+	}
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.allowAllOrigins {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if _, ok := c.origins[origin]; ok {
+		return true
+	}
+
+	host := origin
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	for _, suffix := range c.wildcardSuffixes {
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *cors) isMethodAllowed(method string) bool {
+	if method == http.MethodOptions {
+		return true
+	}
+	for _, m := range c.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) areHeadersAllowed(requested []string) bool {
+	if c.allowAllHeaders {
+		return true
+	}
+	for _, r := range requested {
+		allowed := false
+		for _, h := range c.headers {
+			if strings.EqualFold(h, r) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList splits a comma separated list of header names, discarding empty values
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, h := range strings.Split(value, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	return headers
+}
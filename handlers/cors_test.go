@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCORS(t *testing.T) {
+	Convey("Given a CORS middleware with exact and wildcard origins", t, func() {
+		cfg := CORSConfig{
+			AllowedOrigins:   []string{"https://www.ons.gov.uk", "*.ons.gov.uk"},
+			AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+			AllowedHeaders:   []string{"Content-Type", "X-Florence-Token"},
+			ExposedHeaders:   []string{"ETag"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		}
+		mockHandler := &mockHandler{}
+		target := CORS(cfg)(mockHandler)
+
+		Convey("When a preflight request is made from an allowed origin", func() {
+			r := httptest.NewRequest(http.MethodOptions, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://cy.ons.gov.uk")
+			r.Header.Set(AccessControlRequestMethodHeader, http.MethodPut)
+			r.Header.Set(AccessControlRequestHeadersHeader, "content-type, x-florence-token")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the request is short-circuited with a 204", func() {
+				So(mockHandler.invocations, ShouldEqual, 0)
+				So(w.Code, ShouldEqual, http.StatusNoContent)
+			})
+
+			Convey("And the CORS response headers are set", func() {
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldEqual, "https://cy.ons.gov.uk")
+				So(w.Header().Get(AccessControlAllowMethodsHeader), ShouldEqual, http.MethodPut)
+				So(w.Header().Get(AccessControlAllowHeadersHeader), ShouldEqual, "Content-Type, X-Florence-Token")
+				So(w.Header().Get(AccessControlAllowCredentialsHeader), ShouldEqual, "true")
+				So(w.Header().Get(AccessControlMaxAgeHeader), ShouldEqual, "600")
+				So(w.Header().Values(VaryHeader), ShouldContain, OriginHeader)
+			})
+		})
+
+		Convey("When a preflight request asks for a method that is not allowed", func() {
+			r := httptest.NewRequest(http.MethodOptions, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://www.ons.gov.uk")
+			r.Header.Set(AccessControlRequestMethodHeader, http.MethodDelete)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then no CORS allow headers are set", func() {
+				So(mockHandler.invocations, ShouldEqual, 0)
+				So(w.Code, ShouldEqual, http.StatusNoContent)
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a preflight request asks for a header that is not allowed", func() {
+			r := httptest.NewRequest(http.MethodOptions, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://www.ons.gov.uk")
+			r.Header.Set(AccessControlRequestMethodHeader, http.MethodGet)
+			r.Header.Set(AccessControlRequestHeadersHeader, "X-Other")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then no CORS allow headers are set", func() {
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When an actual request is made from an allowed origin", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://www.ons.gov.uk")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the wrapped handler is called and the CORS headers are set", func() {
+				So(mockHandler.invocations, ShouldEqual, 1)
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldEqual, "https://www.ons.gov.uk")
+				So(w.Header().Get(AccessControlExposeHeadersHeader), ShouldEqual, "ETag")
+				So(w.Header().Get(VaryHeader), ShouldEqual, OriginHeader)
+			})
+		})
+
+		Convey("When an actual request is made from the apex of a wildcard origin", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://ons.gov.uk")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the wrapped handler is called without CORS headers", func() {
+				So(mockHandler.invocations, ShouldEqual, 1)
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldBeEmpty)
+				So(w.Header().Get(VaryHeader), ShouldEqual, OriginHeader)
+			})
+		})
+
+		Convey("When an actual request is made from a disallowed origin", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://evil-ons.gov.uk")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the wrapped handler is called without CORS headers", func() {
+				So(mockHandler.invocations, ShouldEqual, 1)
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a CORS middleware allowing any origin without credentials", t, func() {
+		mockHandler := &mockHandler{}
+		target := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(mockHandler)
+
+		Convey("When an actual request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.Header.Set(OriginHeader, "https://example.com")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the allowed origin is a wildcard", func() {
+				So(w.Header().Get(AccessControlAllowOriginHeader), ShouldEqual, "*")
+				So(w.Header().Get(AccessControlAllowCredentialsHeader), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When an OPTIONS request that is not a preflight is made", func() {
+			r := httptest.NewRequest(http.MethodOptions, "http://localhost:8080", http.NoBody)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the wrapped handler is called", func() {
+				So(mockHandler.invocations, ShouldEqual, 1)
+			})
+		})
+	})
+}
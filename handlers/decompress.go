@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultDecompressionMaxSize is the maximum decompressed request body size used when
+// DecompressionConfig.MaxSize is not set
+const DefaultDecompressionMaxSize int64 = 10 * 1024 * 1024
+
+// DecompressionConfig defines the behaviour of the Decompress middleware.
+//
+// MaxSize limits the size of the decompressed body, to protect against small compressed
+// payloads that expand to exhaust memory (zip bombs).
+type DecompressionConfig struct {
+	MaxSize int64
+}
+
+// Decompress is a middleware that transparently decompresses request bodies sent with a
+// gzip, deflate or zstd Content-Encoding. Requests with any other encoding are rejected
+// with a 415 Unsupported Media Type. Reading beyond the maximum decompressed size returns
+// a *http.MaxBytesError from the request body.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func Decompress(cfg DecompressionConfig) func(http.Handler) http.Handler {
+	maxSize := cfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultDecompressionMaxSize
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			contentEncoding := req.Header.Get(ContentEncodingHeader)
+			if contentEncoding == "" || req.Body == nil || req.Body == http.NoBody {
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			body, status, err := newDecompressingBody(req.Body, contentEncoding, maxSize)
+			if err != nil {
+				log.Error(req.Context(), "failed to decompress request body", err, log.Data{"content_encoding": contentEncoding})
+				dphttp.DrainBody(req)
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+
+			req.Body = http.MaxBytesReader(w, body, maxSize)
+			req.Header.Del(ContentEncodingHeader)
+			req.Header.Del(ContentLengthHeader)
+			req.ContentLength = -1
+
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+// errUnsupportedContentEncoding is returned when a request body uses an encoding that cannot be decoded
+type errUnsupportedContentEncoding string
+
+func (e errUnsupportedContentEncoding) Error() string {
+	return "unsupported content encoding: " + string(e)
+}
+
+// newDecompressingBody wraps body in decoders for each of the encodings in contentEncoding,
+// which are applied in reverse order as per RFC 9110. It returns the status code to respond
+// with if the body cannot be decoded.
+func newDecompressingBody(body io.ReadCloser, contentEncoding string, maxSize int64) (io.ReadCloser, int, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	closers := []io.Closer{body}
+	var r io.Reader = body
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case EncodingIdentity, "":
+			continue
+		case EncodingGzip, "x-gzip":
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			closers = append(closers, gr)
+			r = gr
+		case EncodingDeflate:
+			fr := flate.NewReader(r)
+			closers = append(closers, fr)
+			r = fr
+		case EncodingZstd:
+			zr, err := zstd.NewReader(r, zstd.WithDecoderMaxMemory(uint64(maxSize)), zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			zc := zr.IOReadCloser()
+			closers = append(closers, zc)
+			r = zc
+		default:
+			return nil, http.StatusUnsupportedMediaType, errUnsupportedContentEncoding(encoding)
+		}
+	}
+
+	return &multiCloseReader{Reader: r, closers: closers}, 0, nil
+}
+
+// multiCloseReader closes all of the decoders and the underlying body when it is closed
+type multiCloseReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloseReader) Close() error {
+	var err error
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		if cerr := m.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
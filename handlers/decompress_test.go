@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func gzipBytes(b []byte) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Write(b)
+	gw.Close()
+	return buf.Bytes()
+}
+
+func zstdBytes(b []byte) []byte {
+	buf := &bytes.Buffer{}
+	zw, _ := zstd.NewWriter(buf)
+	zw.Write(b)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	body := strings.Repeat("dimension,option\n", 100)
+
+	var receivedBody []byte
+	var receivedErr error
+	var receivedEncoding string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedEncoding = r.Header.Get(ContentEncodingHeader)
+		receivedBody, receivedErr = io.ReadAll(r.Body)
+	})
+
+	Convey("Given a decompression middleware", t, func() {
+		receivedBody, receivedErr, receivedEncoding = nil, nil, ""
+		target := Decompress(DecompressionConfig{})(handler)
+
+		Convey("When a gzip-encoded request is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", bytes.NewReader(gzipBytes([]byte(body))))
+			r.Header.Set(ContentEncodingHeader, EncodingGzip)
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the handler receives the decompressed body", func() {
+				So(receivedErr, ShouldBeNil)
+				So(string(receivedBody), ShouldEqual, body)
+				So(receivedEncoding, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a request encoded with zstd then gzip is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", bytes.NewReader(gzipBytes(zstdBytes([]byte(body)))))
+			r.Header.Set(ContentEncodingHeader, "zstd, gzip")
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the handler receives the decompressed body", func() {
+				So(receivedErr, ShouldBeNil)
+				So(string(receivedBody), ShouldEqual, body)
+			})
+		})
+
+		Convey("When a request without an encoding is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", strings.NewReader(body))
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the handler receives the body unchanged", func() {
+				So(string(receivedBody), ShouldEqual, body)
+			})
+		})
+
+		Convey("When a request with an unsupported encoding is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", strings.NewReader(body))
+			r.Header.Set(ContentEncodingHeader, "compress")
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then a 415 is returned without calling the handler", func() {
+				So(w.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+				So(receivedBody, ShouldBeNil)
+			})
+		})
+
+		Convey("When a request with an invalid gzip body is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", strings.NewReader(body))
+			r.Header.Set(ContentEncodingHeader, EncodingGzip)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then a 400 is returned without calling the handler", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(receivedBody, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a decompression middleware with a small maximum size", t, func() {
+		receivedBody, receivedErr = nil, nil
+		target := Decompress(DecompressionConfig{MaxSize: 100})(handler)
+
+		Convey("When a request that decompresses beyond the limit is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8080", bytes.NewReader(gzipBytes([]byte(body))))
+			r.Header.Set(ContentEncodingHeader, EncodingGzip)
+			target.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then reading the body fails with a MaxBytesError", func() {
+				var maxBytesErr *http.MaxBytesError
+				So(errors.As(receivedErr, &maxBytesErr), ShouldBeTrue)
+				So(len(receivedBody), ShouldEqual, 100)
+			})
+		})
+	})
+}
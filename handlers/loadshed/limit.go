@@ -0,0 +1,90 @@
+package loadshed
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit determines the maximum number of requests that may be in flight at once
+type Limit interface {
+	// Limit returns the current maximum number of in-flight requests
+	Limit() int
+	// Observe records the latency of a completed request, allowing adaptive limits to adjust
+	Observe(latency time.Duration)
+}
+
+// StaticLimit is a Limit with a fixed maximum number of in-flight requests
+type StaticLimit int
+
+// Limit implements Limit
+func (l StaticLimit) Limit() int {
+	return int(l)
+}
+
+// Observe implements Limit. A static limit ignores latency.
+func (l StaticLimit) Observe(time.Duration) {}
+
+// DefaultAIMDBackoffRatio is the BackoffRatio of limits created by NewAIMDLimit
+const DefaultAIMDBackoffRatio = 0.9
+
+// AIMDLimit is an adaptive Limit using additive-increase/multiplicative-decrease. The limit
+// grows by one for each limit's worth of requests completing within TargetLatency, and is
+// multiplied by BackoffRatio when a request takes longer, staying between Min and Max. The
+// limit decreases at most once for each limit's worth of completed requests, so that a
+// latency spike that slows every in-flight request backs off once rather than once per request.
+type AIMDLimit struct {
+	Min           int
+	Max           int
+	TargetLatency time.Duration
+	BackoffRatio  float64
+
+	mutex   sync.Mutex
+	current float64
+	// backoffWindow is the number of completions remaining before the limit can decrease again
+	backoffWindow int
+}
+
+// NewAIMDLimit creates an AIMDLimit starting at initial, bounded by minLimit and maxLimit
+func NewAIMDLimit(initial, minLimit, maxLimit int, targetLatency time.Duration) *AIMDLimit {
+	if minLimit < 1 {
+		minLimit = 1
+	}
+	if maxLimit < minLimit {
+		maxLimit = minLimit
+	}
+	return &AIMDLimit{
+		Min:           minLimit,
+		Max:           maxLimit,
+		TargetLatency: targetLatency,
+		BackoffRatio:  DefaultAIMDBackoffRatio,
+		current:       math.Max(float64(minLimit), math.Min(float64(maxLimit), float64(initial))),
+	}
+}
+
+// Limit implements Limit
+func (l *AIMDLimit) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int(l.current)
+}
+
+// Observe implements Limit
+func (l *AIMDLimit) Observe(latency time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	slow := latency > l.TargetLatency
+	switch {
+	case slow && l.backoffWindow == 0:
+		// the other requests in flight at the old limit are likely slow for the same reason
+		l.backoffWindow = int(l.current) - 1
+		l.current *= l.BackoffRatio
+	case slow:
+		l.backoffWindow--
+	default:
+		l.backoffWindow = max(0, l.backoffWindow-1)
+		l.current += 1 / math.Max(1, l.current)
+	}
+	l.current = math.Max(float64(l.Min), math.Min(float64(l.Max), l.current))
+}
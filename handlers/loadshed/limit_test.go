@@ -0,0 +1,68 @@
+package loadshed
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAIMDLimit(t *testing.T) {
+	Convey("Given an AIMD limit starting at 10 with a target latency of 100ms", t, func() {
+		limit := NewAIMDLimit(10, 2, 12, 100*time.Millisecond)
+		So(limit.Limit(), ShouldEqual, 10)
+
+		Convey("When just over a limit's worth of requests complete within the target latency", func() {
+			for i := 0; i < 11; i++ {
+				limit.Observe(50 * time.Millisecond)
+			}
+
+			Convey("Then the limit increases by one", func() {
+				So(limit.Limit(), ShouldEqual, 11)
+			})
+		})
+
+		Convey("When many requests complete within the target latency", func() {
+			for i := 0; i < 1000; i++ {
+				limit.Observe(50 * time.Millisecond)
+			}
+
+			Convey("Then the limit does not exceed the maximum", func() {
+				So(limit.Limit(), ShouldEqual, 12)
+			})
+		})
+
+		Convey("When a request exceeds the target latency", func() {
+			limit.Observe(200 * time.Millisecond)
+
+			Convey("Then the limit decreases multiplicatively", func() {
+				So(limit.Limit(), ShouldEqual, 9)
+			})
+		})
+
+		Convey("When a burst of in-flight requests all exceed the target latency", func() {
+			for i := 0; i < 10; i++ {
+				limit.Observe(200 * time.Millisecond)
+			}
+
+			Convey("Then the limit decreases only once", func() {
+				So(limit.Limit(), ShouldEqual, 9)
+			})
+
+			Convey("Then the limit decreases again if the next limit's worth of requests are slow", func() {
+				limit.Observe(200 * time.Millisecond)
+				So(limit.Limit(), ShouldEqual, 8)
+			})
+		})
+
+		Convey("When many requests exceed the target latency", func() {
+			for i := 0; i < 100; i++ {
+				limit.Observe(200 * time.Millisecond)
+			}
+
+			Convey("Then the limit does not fall below the minimum", func() {
+				So(limit.Limit(), ShouldEqual, 2)
+			})
+		})
+	})
+}
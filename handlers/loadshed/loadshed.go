@@ -0,0 +1,196 @@
+package loadshed
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-net/v3/responder"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// RetryAfterHeader is set on responses to shed requests
+const RetryAfterHeader = "Retry-After"
+
+// ErrOverloaded is the error returned in the body of responses to shed requests
+var ErrOverloaded = errors.New("service overloaded, please try again later")
+
+// BypassFunc returns true for requests that should never be shed, such as health checks
+type BypassFunc func(req *http.Request) bool
+
+// BypassPaths returns a BypassFunc matching requests to any of the provided paths
+func BypassPaths(paths ...string) BypassFunc {
+	set := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		set[p] = struct{}{}
+	}
+	return func(req *http.Request) bool {
+		_, ok := set[req.URL.Path]
+		return ok
+	}
+}
+
+// BypassAuthenticatedCallers returns a BypassFunc matching requests with a caller identity in
+// their context, as set by the identity middleware for authenticated publishers and services
+func BypassAuthenticatedCallers() BypassFunc {
+	return func(req *http.Request) bool {
+		return request.IsCallerPresent(req.Context())
+	}
+}
+
+// Config defines the behaviour of a Limiter.
+//
+// Requests beyond the Limit wait in a queue of up to QueueSize requests for at most
+// QueueTimeout. Requests matching any of Bypass are always served and are not counted.
+// RetryAfter, if set, is sent in the Retry-After header of shed requests.
+type Config struct {
+	Limit        Limit
+	QueueSize    int
+	QueueTimeout time.Duration
+	Bypass       []BypassFunc
+	RetryAfter   time.Duration
+}
+
+// Limiter limits the number of requests in flight, shedding excess requests with a
+// 503 Service Unavailable
+type Limiter struct {
+	cfg       Config
+	responder *responder.Responder
+
+	mutex    sync.Mutex
+	inFlight int
+	queue    *list.List
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// New creates a Limiter with the provided config
+func New(cfg Config) *Limiter {
+	if cfg.Limit == nil {
+		panic("loadshed: a Limit must be provided")
+	}
+	return &Limiter{
+		cfg:       cfg,
+		responder: responder.New(),
+		queue:     list.New(),
+	}
+}
+
+// Middleware creates a Limiter with the provided config and returns its middleware.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return New(cfg).Middleware
+}
+
+// Middleware wraps h so that requests are only served while the limiter has capacity
+func (l *Limiter) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, bypass := range l.cfg.Bypass {
+			if bypass(req) {
+				h.ServeHTTP(w, req)
+				return
+			}
+		}
+
+		ctx := req.Context()
+		if !l.acquire(ctx) {
+			log.Warn(ctx, "shedding request as service is overloaded", log.Data{
+				"in_flight": l.InFlight(),
+				"limit":     l.cfg.Limit.Limit(),
+			})
+			if l.cfg.RetryAfter > 0 {
+				w.Header().Set(RetryAfterHeader, strconv.Itoa(int(math.Ceil(l.cfg.RetryAfter.Seconds()))))
+			}
+			l.responder.Error(ctx, w, http.StatusServiceUnavailable, ErrOverloaded)
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			l.cfg.Limit.Observe(time.Since(start))
+			l.release()
+		}()
+
+		h.ServeHTTP(w, req)
+	})
+}
+
+// InFlight returns the number of requests currently being served
+func (l *Limiter) InFlight() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.inFlight
+}
+
+// Queued returns the number of requests currently waiting to be served
+func (l *Limiter) Queued() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.queue.Len()
+}
+
+// acquire reserves capacity for a request, waiting in the queue if necessary. It returns
+// false if the request should be shed.
+func (l *Limiter) acquire(ctx context.Context) bool {
+	l.mutex.Lock()
+	if l.inFlight < l.cfg.Limit.Limit() {
+		l.inFlight++
+		l.mutex.Unlock()
+		return true
+	}
+	if l.queue.Len() >= l.cfg.QueueSize || l.cfg.QueueTimeout <= 0 {
+		l.mutex.Unlock()
+		return false
+	}
+	w := &waiter{ready: make(chan struct{})}
+	elem := l.queue.PushBack(w)
+	l.mutex.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if w.granted {
+		// capacity was granted while timing out, so give it back
+		l.inFlight--
+		l.grant()
+		return false
+	}
+	l.queue.Remove(elem)
+	return false
+}
+
+// release frees the capacity reserved for a request, passing it to the next queued request
+func (l *Limiter) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.inFlight--
+	l.grant()
+}
+
+// grant passes any available capacity to queued requests. The mutex must be held.
+func (l *Limiter) grant() {
+	for l.queue.Len() > 0 && l.inFlight < l.cfg.Limit.Limit() {
+		w := l.queue.Remove(l.queue.Front()).(*waiter)
+		w.granted = true
+		l.inFlight++
+		close(w.ready)
+	}
+}
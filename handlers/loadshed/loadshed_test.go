@@ -0,0 +1,199 @@
+package loadshed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// blockingHandler blocks every request until release is closed
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.started <- struct{}{}
+	<-b.release
+}
+
+func serveAsync(h http.Handler, r *http.Request) (*httptest.ResponseRecorder, *sync.WaitGroup) {
+	w := httptest.NewRecorder()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(w, r)
+	}()
+	return w, wg
+}
+
+func TestLimiterRetryAfter(t *testing.T) {
+	Convey("Given a limiter with a retry after of less than a second", t, func() {
+		handler := newBlockingHandler()
+		target := New(Config{Limit: StaticLimit(1), RetryAfter: 500 * time.Millisecond}).Middleware(handler)
+
+		_, wg := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080/datasets", http.NoBody))
+		<-handler.started
+
+		Convey("When a request is shed", func() {
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/datasets", http.NoBody))
+
+			Convey("Then the retry after is rounded up to a whole second", func() {
+				So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(w.Header().Get(RetryAfterHeader), ShouldEqual, "1")
+			})
+		})
+
+		Reset(func() {
+			close(handler.release)
+			wg.Wait()
+		})
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	Convey("Given a limiter allowing 1 request in flight without a queue", t, func() {
+		handler := newBlockingHandler()
+		limiter := New(Config{
+			Limit:      StaticLimit(1),
+			Bypass:     []BypassFunc{BypassPaths("/health"), BypassAuthenticatedCallers()},
+			RetryAfter: 5 * time.Second,
+		})
+		target := limiter.Middleware(handler)
+
+		_, wg := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080/datasets", http.NoBody))
+		<-handler.started
+		So(limiter.InFlight(), ShouldEqual, 1)
+
+		Convey("When another request is made", func() {
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/datasets", http.NoBody))
+
+			Convey("Then it is shed with a 503", func() {
+				So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(w.Header().Get(RetryAfterHeader), ShouldEqual, "5")
+				So(w.Body.String(), ShouldContainSubstring, ErrOverloaded.Error())
+			})
+		})
+
+		Convey("When a health check is made", func() {
+			_, hwg := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080/health", http.NoBody))
+
+			Convey("Then it bypasses the limit", func() {
+				<-handler.started
+				So(limiter.InFlight(), ShouldEqual, 1)
+				close(handler.release)
+				hwg.Wait()
+			})
+		})
+
+		Convey("When an authenticated request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/datasets", http.NoBody)
+			r = r.WithContext(request.SetCaller(r.Context(), "publisher@ons.gov.uk"))
+			_, awg := serveAsync(target, r)
+
+			Convey("Then it bypasses the limit", func() {
+				<-handler.started
+				close(handler.release)
+				awg.Wait()
+			})
+		})
+
+		Reset(func() {
+			select {
+			case <-handler.release:
+			default:
+				close(handler.release)
+			}
+			wg.Wait()
+			So(limiter.InFlight(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Given a limiter allowing 1 request in flight with a queue of 1", t, func() {
+		handler := newBlockingHandler()
+		limiter := New(Config{
+			Limit:        StaticLimit(1),
+			QueueSize:    1,
+			QueueTimeout: time.Second,
+		})
+		target := limiter.Middleware(handler)
+
+		w1, wg1 := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody))
+		<-handler.started
+
+		Convey("When a second request is made", func() {
+			w2, wg2 := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody))
+			for limiter.Queued() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			Convey("Then a third request is shed as the queue is full", func() {
+				w3 := httptest.NewRecorder()
+				target.ServeHTTP(w3, httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody))
+				So(w3.Code, ShouldEqual, http.StatusServiceUnavailable)
+			})
+
+			Convey("Then the queued request is served once the first completes", func() {
+				close(handler.release)
+				wg1.Wait()
+				wg2.Wait()
+				So(w1.Code, ShouldEqual, http.StatusOK)
+				So(w2.Code, ShouldEqual, http.StatusOK)
+				So(limiter.InFlight(), ShouldEqual, 0)
+				So(limiter.Queued(), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			select {
+			case <-handler.release:
+			default:
+				close(handler.release)
+			}
+			wg1.Wait()
+		})
+	})
+
+	Convey("Given a limiter with a short queue timeout", t, func() {
+		handler := newBlockingHandler()
+		limiter := New(Config{
+			Limit:        StaticLimit(1),
+			QueueSize:    1,
+			QueueTimeout: 10 * time.Millisecond,
+		})
+		target := limiter.Middleware(handler)
+
+		_, wg := serveAsync(target, httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody))
+		<-handler.started
+
+		Convey("When a queued request times out", func() {
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody))
+
+			Convey("Then it is shed and removed from the queue", func() {
+				So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(limiter.Queued(), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			close(handler.release)
+			wg.Wait()
+		})
+	})
+}
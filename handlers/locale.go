@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/links"
+	"github.com/ONSdigital/dp-net/v3/request"
+)
+
+// NegotiateLocale is a middleware that negotiates the locale of the response from the request, see
+// request.NegotiateLocale, and stores it in the request context under request.LocaleContextKey. The
+// Content-Language response header is set to the negotiated locale, which the wrapped handler can
+// override, and Accept-Language and Cookie, as the locale may be chosen by the locale cookie, are added
+// to the Vary response header.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func NegotiateLocale(cfg request.LocaleConfig) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			locale := request.NegotiateLocale(req, cfg)
+			req = req.WithContext(request.SetLocale(req.Context(), locale))
+
+			header := w.Header()
+			header.Set(request.ContentLanguageHeader, locale)
+			addVary(header, request.AcceptLanguageHeader)
+			addVary(header, "Cookie")
+
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+// CanonicalLocaleRedirect is a middleware that redirects GET and HEAD requests to the URL of the locale in the
+// 'lang' cookie, when it is a supported locale that differs from the locale of the requested URL, e.g. a request to
+// "www.ons.gov.uk" with a "cy" cookie is redirected to "cy.ons.gov.uk". The redirect is temporary, as it depends on
+// the cookie, and other requests are not redirected.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func CanonicalLocaleRedirect(urls links.LocaleURLs) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			c, err := req.Cookie(request.LocaleCookieKey)
+			if err != nil || c.Value == "" {
+				h.ServeHTTP(w, req)
+				return
+			}
+			cookieLocale, ok := request.MatchLocale(c.Value, urls.Locales.Supported)
+			if !ok {
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			// the absolute URL of the request, as requested by the client
+			u := *req.URL
+			u.Host = req.Host
+			u.Scheme = "http"
+			if req.TLS != nil {
+				u.Scheme = "https"
+			}
+			if proto := req.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+				u.Scheme = proto
+			}
+
+			if urls.LocaleOf(&u) == cookieLocale {
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			addVary(w.Header(), "Cookie")
+			http.Redirect(w, req, urls.URLFor(&u, cookieLocale).String(), http.StatusFound)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/links"
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	Convey("Given a handler wrapped by the NegotiateLocale middleware", t, func() {
+		var locale interface{}
+		handler := NegotiateLocale(request.LocaleConfig{Supported: []string{"en", "cy", "fr-CA"}})(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				locale = req.Context().Value(request.LocaleContextKey)
+			}))
+
+		Convey("When a request is made with an Accept-Language header", func() {
+			req := httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody)
+			req.Header.Set("Accept-Language", "de, fr-ca;q=0.9, en;q=0.5")
+			w := httptest.NewRecorder()
+			w.Header().Add("Vary", "Accept-Language")
+			handler.ServeHTTP(w, req)
+
+			Convey("Then the negotiated locale is stored in the request context", func() {
+				So(locale, ShouldEqual, "fr-CA")
+			})
+
+			Convey("And the Content-Language and Vary response headers are set", func() {
+				So(w.Header().Get("Content-Language"), ShouldEqual, "fr-CA")
+				So(w.Header().Values("Vary"), ShouldResemble, []string{"Accept-Language", "Cookie"})
+			})
+		})
+
+		Convey("When a request is made with no supported locale", func() {
+			req := httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then the default locale is used", func() {
+				So(locale, ShouldEqual, "en")
+				So(w.Header().Get("Content-Language"), ShouldEqual, "en")
+			})
+		})
+	})
+
+	Convey("The Content-Language response header can be overridden by the wrapped handler", t, func() {
+		handler := NegotiateLocale(request.DefaultLocaleConfig)(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Language", "en")
+			}))
+
+		req := httptest.NewRequest(http.MethodGet, "/datasets?lang=cy", http.NoBody)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		So(w.Header().Get("Content-Language"), ShouldEqual, "en")
+	})
+}
+
+func TestCanonicalLocaleRedirect(t *testing.T) {
+	Convey("Given a handler wrapped by the CanonicalLocaleRedirect middleware", t, func() {
+		called := false
+		handler := CanonicalLocaleRedirect(links.DefaultLocaleURLs)(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				called = true
+			}))
+
+		newRequest := func(method, target, lang string) *http.Request {
+			req := httptest.NewRequest(method, target, http.NoBody)
+			if lang != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: lang})
+			}
+			return req
+		}
+
+		Convey("When the lang cookie conflicts with the subdomain", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest(http.MethodGet, "http://www.ons.gov.uk/economy?page=2", "cy"))
+
+			Convey("Then the request is redirected to the cookie's locale", func() {
+				So(called, ShouldBeFalse)
+				So(w.Code, ShouldEqual, http.StatusFound)
+				So(w.Header().Get("Location"), ShouldEqual, "http://cy.ons.gov.uk/economy?page=2")
+				So(w.Header().Get("Vary"), ShouldEqual, "Cookie")
+			})
+		})
+
+		Convey("When the request was forwarded over https", func() {
+			req := newRequest(http.MethodGet, "http://cy.ons.gov.uk/economy", "en")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then the redirect is to https", func() {
+				So(w.Header().Get("Location"), ShouldEqual, "https://www.ons.gov.uk/economy")
+			})
+		})
+
+		Convey("When the request is not redirected", func() {
+			for _, req := range []*http.Request{
+				newRequest(http.MethodGet, "http://cy.ons.gov.uk/economy", "cy"),
+				newRequest(http.MethodGet, "http://cy.ons.gov.uk/economy", ""),
+				newRequest(http.MethodGet, "http://cy.ons.gov.uk/economy", "fr"),
+				newRequest(http.MethodPost, "http://cy.ons.gov.uk/economy", "en"),
+			} {
+				called = false
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				So(called, ShouldBeTrue)
+				So(w.Code, ShouldEqual, http.StatusOK)
+			}
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+)
+
+// ForwardedForHeader is the header listing the client and proxy addresses of a request
+const ForwardedForHeader = request.ForwardedForHeader
+
+// KeyFunc returns the key a request is rate limited by. Requests with an empty key are not limited.
+type KeyFunc func(req *http.Request) string
+
+// KeyByClientIP returns a KeyFunc that limits requests by client IP address. The
+// X-Forwarded-For header is only honoured when the request comes from one of the trusted
+// proxies (IP addresses or CIDRs), and is walked from right to left to find the first address
+// that is not a trusted proxy, see request.ClientIPConfig. Invalid trusted proxies are ignored.
+func KeyByClientIP(trustedProxies ...string) KeyFunc {
+	var trusted request.TrustedProxies
+	for _, proxy := range trustedProxies {
+		if parsed, err := request.ParseTrustedProxies(proxy); err == nil {
+			trusted = append(trusted, parsed...)
+		}
+	}
+	return KeyByClientIPConfig(request.ClientIPConfig{TrustedProxies: trusted})
+}
+
+// KeyByClientIPConfig returns a KeyFunc that limits requests by client IP address, resolved
+// with the trusted proxies and forwarded header of the config
+func KeyByClientIPConfig(cfg request.ClientIPConfig) KeyFunc {
+	return func(req *http.Request) string {
+		if ip := cfg.ClientIP(req); ip != "" {
+			return "ip:" + ip
+		}
+		return ""
+	}
+}
+
+// KeyByCaller returns a KeyFunc that limits requests by the caller identity in the request
+// context, as set by the identity middleware
+func KeyByCaller() KeyFunc {
+	return func(req *http.Request) string {
+		if caller := request.Caller(req.Context()); caller != "" {
+			return "caller:" + caller
+		}
+		return ""
+	}
+}
+
+// KeyByServiceToken returns a KeyFunc that limits requests by a hash of the service token in
+// the Authorization header
+func KeyByServiceToken() KeyFunc {
+	return func(req *http.Request) string {
+		token, err := request.GetAuthToken(req)
+		if err != nil {
+			return ""
+		}
+		hash := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(hash[:])
+	}
+}
+
+// FirstKey returns a KeyFunc that uses the first non-empty key returned by keyFuncs, e.g. to
+// limit authenticated callers by identity and anonymous callers by IP address
+func FirstKey(keyFuncs ...KeyFunc) KeyFunc {
+	return func(req *http.Request) string {
+		for _, keyFunc := range keyFuncs {
+			if key := keyFunc(req); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
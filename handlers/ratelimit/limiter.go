@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Result is the outcome of a rate limiting decision for a single request
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key is allowed
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// TokenBucket is a Limiter that allows bursts of up to Burst requests, refilled at a rate
+// of Limit requests per Period
+type TokenBucket struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+	Store  Store
+	now    func() time.Time
+}
+
+// NewTokenBucket creates a token bucket Limiter. If burst is not positive, limit is used.
+func NewTokenBucket(limit int, period time.Duration, burst int, store Store) *TokenBucket {
+	if burst <= 0 {
+		burst = limit
+	}
+	return &TokenBucket{
+		Limit:  limit,
+		Period: period,
+		Burst:  burst,
+		Store:  store,
+		now:    time.Now,
+	}
+}
+
+// Allow implements Limiter
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	rate := float64(l.Limit) / l.Period.Seconds()
+	burst := float64(l.Burst)
+	// the bucket is full again after burst/rate seconds, after which the state is not needed
+	ttl := time.Duration(burst / rate * float64(time.Second))
+
+	allowed := false
+	state, err := l.Store.Update(ctx, key, ttl, func(s State) State {
+		tokens := burst
+		if !s.Last.IsZero() {
+			tokens = math.Min(burst, s.Tokens+now.Sub(s.Last).Seconds()*rate)
+		}
+		if tokens >= 1 {
+			tokens--
+			allowed = true
+		}
+		return State{Tokens: tokens, Last: now}
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Floor(state.Tokens)),
+		Reset:     secondsToDuration((burst - state.Tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - state.Tokens) / rate)
+	}
+	return result, nil
+}
+
+// SlidingWindow is a Limiter that allows Limit requests in any Window, approximated by
+// weighting the count of the previous fixed window by how much it overlaps the sliding window
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+	Store  Store
+	now    func() time.Time
+}
+
+// NewSlidingWindow creates a sliding window Limiter
+func NewSlidingWindow(limit int, window time.Duration, store Store) *SlidingWindow {
+	return &SlidingWindow{
+		Limit:  limit,
+		Window: window,
+		Store:  store,
+		now:    time.Now,
+	}
+}
+
+// Allow implements Limiter
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	windowStart := now.Truncate(l.Window)
+	// the previous window's count is needed until the end of the next window
+	ttl := 2 * l.Window
+
+	allowed := false
+	var estimate float64
+	state, err := l.Store.Update(ctx, key, ttl, func(s State) State {
+		switch {
+		case s.WindowStart.Equal(windowStart):
+		case s.WindowStart.Add(l.Window).Equal(windowStart):
+			s = State{WindowStart: windowStart, PrevCount: s.Count}
+		default:
+			s = State{WindowStart: windowStart}
+		}
+
+		estimate = l.estimate(s, now)
+		if estimate+1 <= float64(l.Limit) {
+			s.Count++
+			estimate++
+			allowed = true
+		}
+		return s
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     l.Limit,
+		Remaining: int(math.Max(0, math.Floor(float64(l.Limit)-estimate))),
+		Reset:     windowStart.Add(l.Window).Sub(now),
+	}
+	if !allowed {
+		result.RetryAfter = l.retryAfter(state, now)
+	}
+	return result, nil
+}
+
+// estimate returns the approximate number of requests in the sliding window ending now
+func (l *SlidingWindow) estimate(s State, now time.Time) float64 {
+	elapsed := now.Sub(s.WindowStart).Seconds() / l.Window.Seconds()
+	return float64(s.PrevCount)*(1-elapsed) + float64(s.Count)
+}
+
+// retryAfter returns how long until the estimated number of requests allows another request
+func (l *SlidingWindow) retryAfter(s State, now time.Time) time.Duration {
+	limit := float64(l.Limit - 1)
+	windowEnd := s.WindowStart.Add(l.Window)
+
+	// solve PrevCount*(1-elapsed) + Count <= Limit-1 for elapsed within the current window
+	if s.PrevCount > 0 && float64(s.Count) <= limit {
+		elapsed := 1 - (limit-float64(s.Count))/float64(s.PrevCount)
+		return s.WindowStart.Add(time.Duration(elapsed * float64(l.Window))).Sub(now)
+	}
+
+	// otherwise solve Count*(1-elapsed) <= Limit-1 for elapsed within the next window
+	elapsed := 0.0
+	if s.Count > 0 {
+		elapsed = math.Max(0, 1-limit/float64(s.Count))
+	}
+	return windowEnd.Add(time.Duration(elapsed * float64(l.Window))).Sub(now)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestStore(clock *fakeClock) *MemoryStore {
+	store := NewMemoryStore(time.Minute)
+	store.now = clock.Now
+	return store
+}
+
+func TestTokenBucket(t *testing.T) {
+	Convey("Given a token bucket allowing 2 requests per second with a burst of 3", t, func() {
+		ctx := context.Background()
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		limiter := NewTokenBucket(2, time.Second, 3, newTestStore(clock))
+		limiter.now = clock.Now
+
+		Convey("Then a burst of 3 requests is allowed", func() {
+			for i := 2; i >= 0; i-- {
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+				So(result.Limit, ShouldEqual, 3)
+				So(result.Remaining, ShouldEqual, i)
+			}
+
+			Convey("And the next request is rejected with a retry after", func() {
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeFalse)
+				So(result.RetryAfter, ShouldEqual, 500*time.Millisecond)
+				So(result.Reset, ShouldEqual, 1500*time.Millisecond)
+			})
+
+			Convey("And another request is allowed once a token is refilled", func() {
+				clock.Advance(500 * time.Millisecond)
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+				So(result.Remaining, ShouldEqual, 0)
+			})
+
+			Convey("And requests for other keys are not affected", func() {
+				result, err := limiter.Allow(ctx, "other")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	Convey("Given a sliding window allowing 4 requests per minute", t, func() {
+		ctx := context.Background()
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		limiter := NewSlidingWindow(4, time.Minute, newTestStore(clock))
+		limiter.now = clock.Now
+
+		Convey("Then 4 requests are allowed in the first window", func() {
+			for i := 3; i >= 0; i-- {
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+				So(result.Remaining, ShouldEqual, i)
+			}
+
+			Convey("And the next request is rejected until the previous window has mostly slid out", func() {
+				clock.Advance(30 * time.Second)
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeFalse)
+				So(result.RetryAfter, ShouldEqual, 45*time.Second)
+			})
+
+			Convey("And half of the limit is available half way through the next window", func() {
+				clock.Advance(90 * time.Second)
+				for i := 0; i < 2; i++ {
+					result, err := limiter.Allow(ctx, "key")
+					So(err, ShouldBeNil)
+					So(result.Allowed, ShouldBeTrue)
+				}
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeFalse)
+				So(result.RetryAfter, ShouldEqual, 15*time.Second)
+			})
+
+			Convey("And the full limit is available after two windows", func() {
+				clock.Advance(2 * time.Minute)
+				result, err := limiter.Allow(ctx, "key")
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+				So(result.Remaining, ShouldEqual, 3)
+			})
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/responder"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Rate limit header constants
+const (
+	RetryAfterHeader         = "Retry-After"
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// ErrRateLimited is the error returned in the body of responses to rate limited requests
+var ErrRateLimited = errors.New("too many requests")
+
+// Config defines the behaviour of the rate limiting Middleware. Requests are limited by the
+// key returned by KeyFunc, using Limiter to decide whether each request is allowed.
+type Config struct {
+	Limiter Limiter
+	KeyFunc KeyFunc
+}
+
+// Middleware is a rate limiting middleware which responds with a 429 Too Many Requests and a
+// Retry-After header when the limit for a key has been reached. The RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers are set on every limited response.
+//
+// If the Limiter returns an error (e.g. its shared store is unavailable) the request is allowed.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Limiter == nil {
+		panic("ratelimit: a Limiter must be provided")
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = KeyByClientIP()
+	}
+	resp := responder.New()
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+
+			key := cfg.KeyFunc(req)
+			if key == "" {
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			result, err := cfg.Limiter.Allow(ctx, key)
+			if err != nil {
+				log.Error(ctx, "rate limiter failed, allowing request", err)
+				h.ServeHTTP(w, req)
+				return
+			}
+
+			header := w.Header()
+			header.Set(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+			header.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+			header.Set(RateLimitResetHeader, seconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set(RetryAfterHeader, seconds(result.RetryAfter))
+				log.Info(ctx, "rate limit exceeded", log.Data{"retry_after": result.RetryAfter.String()})
+				resp.Error(ctx, w, http.StatusTooManyRequests, ErrRateLimited)
+				return
+			}
+
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+// seconds formats a duration as a whole number of seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type limiterFunc func(ctx context.Context, key string) (Result, error)
+
+func (f limiterFunc) Allow(ctx context.Context, key string) (Result, error) {
+	return f(ctx, key)
+}
+
+func TestMiddleware(t *testing.T) {
+	handlerCalls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+	})
+
+	Convey("Given a rate limit middleware allowing 1 request per minute per IP", t, func() {
+		handlerCalls = 0
+		target := Middleware(Config{
+			Limiter: NewTokenBucket(1, time.Minute, 1, NewMemoryStore(0)),
+			KeyFunc: KeyByClientIP(),
+		})(handler)
+
+		Convey("When the first request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the request is allowed and the rate limit headers are set", func() {
+				So(handlerCalls, ShouldEqual, 1)
+				So(w.Header().Get(RateLimitLimitHeader), ShouldEqual, "1")
+				So(w.Header().Get(RateLimitRemainingHeader), ShouldEqual, "0")
+				So(w.Header().Get(RateLimitResetHeader), ShouldEqual, "60")
+			})
+
+			Convey("And a second request is rejected with a 429", func() {
+				w := httptest.NewRecorder()
+				target.ServeHTTP(w, r)
+
+				So(handlerCalls, ShouldEqual, 1)
+				So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+				So(w.Header().Get(RetryAfterHeader), ShouldEqual, "60")
+				So(w.Body.String(), ShouldEqual, `{"errors":["too many requests"]}`)
+			})
+		})
+	})
+
+	Convey("Given a rate limit middleware whose limiter returns an error", t, func() {
+		handlerCalls = 0
+		target := Middleware(Config{
+			Limiter: limiterFunc(func(ctx context.Context, key string) (Result, error) {
+				return Result{}, errors.New("store unavailable")
+			}),
+		})(handler)
+
+		Convey("When a request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the request is allowed", func() {
+				So(handlerCalls, ShouldEqual, 1)
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+}
+
+func TestKeyFuncs(t *testing.T) {
+	Convey("Given KeyByClientIP with a trusted proxy range", t, func() {
+		keyFunc := KeyByClientIP("10.0.0.0/8", "192.168.0.1")
+
+		Convey("Then X-Forwarded-For is ignored for requests from untrusted addresses", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.RemoteAddr = "203.0.113.1:1234"
+			r.Header.Set(ForwardedForHeader, "198.51.100.1")
+			So(keyFunc(r), ShouldEqual, "ip:203.0.113.1")
+		})
+
+		Convey("Then X-Forwarded-For is walked right to left past trusted proxies", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.RemoteAddr = "10.1.1.1:1234"
+			r.Header.Set(ForwardedForHeader, "198.51.100.99, 198.51.100.1, 192.168.0.1")
+			r.Header.Add(ForwardedForHeader, "10.2.2.2")
+			So(keyFunc(r), ShouldEqual, "ip:198.51.100.1")
+		})
+
+		Convey("Then a Forwarded header set by the client is ignored", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.RemoteAddr = "10.0.0.5:1234"
+			r.Header.Set(ForwardedForHeader, "203.0.113.9")
+			r.Header.Set(request.ForwardedHeader, "for=198.51.100.1")
+			So(keyFunc(r), ShouldEqual, "ip:203.0.113.9")
+		})
+	})
+
+	Convey("Given KeyByClientIPConfig trusting the Forwarded header", t, func() {
+		trusted, err := request.ParseTrustedProxies("10.0.0.0/8")
+		So(err, ShouldBeNil)
+		keyFunc := KeyByClientIPConfig(request.ClientIPConfig{TrustedProxies: trusted, Header: request.ForwardedHeader})
+
+		Convey("Then an X-Forwarded-For header set by the client is ignored", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			r.RemoteAddr = "10.0.0.5:1234"
+			r.Header.Set(request.ForwardedHeader, "for=203.0.113.9")
+			r.Header.Set(ForwardedForHeader, "198.51.100.1")
+			So(keyFunc(r), ShouldEqual, "ip:203.0.113.9")
+		})
+	})
+
+	Convey("Given KeyByCaller", t, func() {
+		keyFunc := KeyByCaller()
+
+		Convey("Then the caller identity is used as the key", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			So(keyFunc(r), ShouldBeEmpty)
+			r = r.WithContext(request.SetCaller(r.Context(), "publisher@ons.gov.uk"))
+			So(keyFunc(r), ShouldEqual, "caller:publisher@ons.gov.uk")
+		})
+	})
+
+	Convey("Given FirstKey with the service token and client IP", t, func() {
+		keyFunc := FirstKey(KeyByServiceToken(), KeyByClientIP())
+
+		Convey("Then a hash of the service token is used when present", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			request.AddServiceTokenHeader(r, "secret")
+			key := keyFunc(r)
+			So(key, ShouldStartWith, "token:")
+			So(key, ShouldNotContainSubstring, "secret")
+		})
+
+		Convey("Then the client IP is used otherwise", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			So(keyFunc(r), ShouldEqual, "ip:192.0.2.1")
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCleanupInterval is how often a MemoryStore evicts expired keys
+const DefaultCleanupInterval = time.Minute
+
+// State is the rate limiting state stored for a single key. Which fields are used depends
+// on the algorithm of the limiter.
+type State struct {
+	Tokens      float64   `json:"tokens,omitempty"`
+	Last        time.Time `json:"last,omitempty"`
+	Count       int       `json:"count,omitempty"`
+	PrevCount   int       `json:"prev_count,omitempty"`
+	WindowStart time.Time `json:"window_start,omitempty"`
+}
+
+// Store persists rate limiting state. Implementations backed by a shared store allow limits
+// to be enforced across multiple instances of a service.
+type Store interface {
+	// Update atomically reads the state for key (the zero State if there is none), applies fn
+	// to it and stores the result, which expires after ttl. It returns the updated state.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) (State, error)
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// MemoryStore is an in-memory Store for limiting a single instance of a service.
+// Expired keys are evicted periodically during updates.
+type MemoryStore struct {
+	mutex           sync.Mutex
+	entries         map[string]*memoryEntry
+	cleanupInterval time.Duration
+	lastCleanup     time.Time
+	now             func() time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore which evicts expired keys every cleanupInterval,
+// or every DefaultCleanupInterval if cleanupInterval is not positive
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	if cleanupInterval <= 0 {
+		cleanupInterval = DefaultCleanupInterval
+	}
+	return &MemoryStore{
+		entries:         map[string]*memoryEntry{},
+		cleanupInterval: cleanupInterval,
+		now:             time.Now,
+	}
+}
+
+// Update implements Store
+func (s *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) (State, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	if s.lastCleanup.IsZero() {
+		s.lastCleanup = now
+	} else if now.Sub(s.lastCleanup) >= s.cleanupInterval {
+		s.evictExpired(now)
+	}
+
+	var state State
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		state = entry.state
+	}
+
+	state = fn(state)
+	s.entries[key] = &memoryEntry{state: state, expires: now.Add(ttl)}
+	return state, nil
+}
+
+// Len returns the number of keys currently held by the store
+func (s *MemoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.entries)
+}
+
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastCleanup = now
+}
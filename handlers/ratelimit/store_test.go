@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryStore(t *testing.T) {
+	Convey("Given a memory store", t, func() {
+		ctx := context.Background()
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		store := newTestStore(clock)
+		increment := func(s State) State {
+			s.Count++
+			return s
+		}
+
+		Convey("When a key is updated twice", func() {
+			_, err := store.Update(ctx, "a", time.Second, increment)
+			So(err, ShouldBeNil)
+			state, err := store.Update(ctx, "a", time.Second, increment)
+			So(err, ShouldBeNil)
+
+			Convey("Then the stored state is passed to the second update", func() {
+				So(state.Count, ShouldEqual, 2)
+			})
+
+			Convey("And the state is reset once it has expired", func() {
+				clock.Advance(time.Second)
+				state, err := store.Update(ctx, "a", time.Second, increment)
+				So(err, ShouldBeNil)
+				So(state.Count, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When keys have expired and the cleanup interval has passed", func() {
+			store.Update(ctx, "a", time.Second, increment)
+			store.Update(ctx, "b", time.Second, increment)
+			So(store.Len(), ShouldEqual, 2)
+
+			clock.Advance(time.Minute)
+			store.Update(ctx, "c", time.Second, increment)
+
+			Convey("Then the expired keys are evicted", func() {
+				So(store.Len(), ShouldEqual, 1)
+			})
+		})
+	})
+}
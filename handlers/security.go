@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Security header constants
+const (
+	StrictTransportSecurityHeader         = "Strict-Transport-Security"
+	ContentSecurityPolicyHeader           = "Content-Security-Policy"
+	ContentSecurityPolicyReportOnlyHeader = "Content-Security-Policy-Report-Only"
+	ContentTypeOptionsHeader              = "X-Content-Type-Options"
+	ReferrerPolicyHeader                  = "Referrer-Policy"
+	FrameOptionsHeader                    = "X-Frame-Options"
+)
+
+// CSPNoncePlaceholder is replaced in a content security policy with the nonce generated for each request
+const CSPNoncePlaceholder = "{nonce}"
+
+const cspNonceSize = 16
+
+// SecurityHeadersConfig defines the values of the headers set by the SecurityHeaders middleware.
+// Headers with an empty value (or a zero HSTSMaxAge) are not set.
+//
+// ContentSecurityPolicy may contain CSPNoncePlaceholder, e.g. "script-src 'nonce-{nonce}'", in which
+// case a new nonce is generated for each request and made available to handlers through CSPNonce.
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubDomains bool
+	HSTSPreload           bool
+	ContentSecurityPolicy string
+	CSPReportOnly         bool
+	CSPReportURI          string
+	ContentTypeOptions    string
+	ReferrerPolicy        string
+	FrameOptions          string
+}
+
+// DefaultSecurityHeadersConfig returns a SecurityHeadersConfig with sensible defaults for frontend services
+func DefaultSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubDomains: true,
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		ContentTypeOptions:    "nosniff",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		FrameOptions:          "DENY",
+	}
+}
+
+// SecurityHeaders is a middleware that sets the configured security headers on every response.
+// Handlers may override any of the headers before writing the response.
+// This function complies with alice middleware Constructor type: func(http.Handler) -> (http.Handler)
+func SecurityHeaders(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
+	hsts := hstsValue(cfg)
+
+	cspHeader := ContentSecurityPolicyHeader
+	if cfg.CSPReportOnly {
+		cspHeader = ContentSecurityPolicyReportOnlyHeader
+	}
+	csp := cfg.ContentSecurityPolicy
+	if csp != "" && cfg.CSPReportURI != "" {
+		csp = strings.TrimSuffix(strings.TrimSpace(csp), ";") + "; report-uri " + cfg.CSPReportURI
+	}
+	useNonce := strings.Contains(csp, CSPNoncePlaceholder)
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			header := w.Header()
+
+			if hsts != "" {
+				header.Set(StrictTransportSecurityHeader, hsts)
+			}
+			if cfg.ContentTypeOptions != "" {
+				header.Set(ContentTypeOptionsHeader, cfg.ContentTypeOptions)
+			}
+			if cfg.ReferrerPolicy != "" {
+				header.Set(ReferrerPolicyHeader, cfg.ReferrerPolicy)
+			}
+			if cfg.FrameOptions != "" {
+				header.Set(FrameOptionsHeader, cfg.FrameOptions)
+			}
+
+			if csp != "" {
+				policy := csp
+				if useNonce {
+					nonce, err := generateCSPNonce()
+					if err != nil {
+						log.Error(req.Context(), "failed to generate content security policy nonce", err)
+						http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+						return
+					}
+					policy = strings.ReplaceAll(policy, CSPNoncePlaceholder, nonce)
+					req = req.WithContext(context.WithValue(req.Context(), request.CSPNonceContextKey, nonce))
+				}
+				header.Set(cspHeader, policy)
+			}
+
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+// CSPNonce returns the content security policy nonce generated for the request by the
+// SecurityHeaders middleware, or an empty string if no nonce was generated
+func CSPNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(request.CSPNonceContextKey).(string)
+	return nonce
+}
+
+func hstsValue(cfg SecurityHeadersConfig) string {
+	if cfg.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", int64(cfg.HSTSMaxAge.Seconds()))
+	if cfg.HSTSIncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+func generateCSPNonce() (string, error) {
+	b := make([]byte, cspNonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
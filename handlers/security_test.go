@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	Convey("Given a security headers middleware with the default config", t, func() {
+		mockHandler := &mockHandler{}
+		target := SecurityHeaders(DefaultSecurityHeadersConfig())(mockHandler)
+
+		Convey("When a request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the wrapped handler is called 1 time", func() {
+				So(mockHandler.invocations, ShouldEqual, 1)
+			})
+
+			Convey("And the security headers are set", func() {
+				So(w.Header().Get(StrictTransportSecurityHeader), ShouldEqual, "max-age=31536000; includeSubDomains")
+				So(w.Header().Get(ContentTypeOptionsHeader), ShouldEqual, "nosniff")
+				So(w.Header().Get(ReferrerPolicyHeader), ShouldEqual, "strict-origin-when-cross-origin")
+				So(w.Header().Get(FrameOptionsHeader), ShouldEqual, "DENY")
+			})
+
+			Convey("And the content security policy contains the nonce stored in the request context", func() {
+				nonce := CSPNonce(mockHandler.ctx)
+				So(nonce, ShouldNotBeEmpty)
+				So(w.Header().Get(ContentSecurityPolicyHeader), ShouldContainSubstring, "'nonce-"+nonce+"'")
+				So(w.Header().Get(ContentSecurityPolicyHeader), ShouldNotContainSubstring, CSPNoncePlaceholder)
+			})
+
+			Convey("And a different nonce is generated for the next request", func() {
+				nonce := CSPNonce(mockHandler.ctx)
+				target.ServeHTTP(httptest.NewRecorder(), r)
+				So(CSPNonce(mockHandler.ctx), ShouldNotEqual, nonce)
+			})
+		})
+	})
+
+	Convey("Given a security headers middleware in report-only mode", t, func() {
+		cfg := SecurityHeadersConfig{
+			HSTSMaxAge:            time.Hour,
+			HSTSPreload:           true,
+			ContentSecurityPolicy: "default-src 'self';",
+			CSPReportOnly:         true,
+			CSPReportURI:          "/csp-report",
+		}
+		mockHandler := &mockHandler{}
+		target := SecurityHeaders(cfg)(mockHandler)
+
+		Convey("When a request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", http.NoBody)
+			w := httptest.NewRecorder()
+			target.ServeHTTP(w, r)
+
+			Convey("Then the policy is set in the report-only header", func() {
+				So(w.Header().Get(ContentSecurityPolicyHeader), ShouldBeEmpty)
+				So(w.Header().Get(ContentSecurityPolicyReportOnlyHeader), ShouldEqual, "default-src 'self'; report-uri /csp-report")
+			})
+
+			Convey("And no nonce is generated", func() {
+				So(CSPNonce(mockHandler.ctx), ShouldBeEmpty)
+			})
+
+			Convey("And unconfigured headers are not set", func() {
+				So(w.Header().Get(StrictTransportSecurityHeader), ShouldEqual, "max-age=3600; preload")
+				So(w.Header().Get(FrameOptionsHeader), ShouldBeEmpty)
+				So(w.Header().Get(ReferrerPolicyHeader), ShouldBeEmpty)
+				So(w.Header().Get(ContentTypeOptionsHeader), ShouldBeEmpty)
+			})
+		})
+	})
+}
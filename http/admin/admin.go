@@ -0,0 +1,252 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	"github.com/ONSdigital/dp-net/v3/responder"
+	"github.com/gorilla/mux"
+)
+
+// Admin endpoint paths
+const (
+	PprofPath       = "/debug/pprof/"
+	RuntimePath     = "/admin/runtime"
+	BuildInfoPath   = "/admin/build"
+	MiddlewarePath  = "/admin/middleware"
+	ConfigPath      = "/admin/config"
+	ConnectionsPath = "/admin/connections"
+	LogLevelPath    = "/admin/log-level"
+)
+
+// ErrBuildInfoUnavailable is returned when the binary was not built with module support
+var ErrBuildInfoUnavailable = errors.New("build info unavailable")
+
+var started = time.Now()
+
+// AuthFunc wraps an admin handler with an authorisation check, e.g. handlers.CheckIdentity
+type AuthFunc func(handle func(http.ResponseWriter, *http.Request)) http.HandlerFunc
+
+// Config is the configuration for the admin router
+type Config struct {
+	// Server is the server whose middleware, config and connections are reported. If nil, the
+	// middleware, config and connections endpoints are not registered.
+	Server *dphttp.Server
+	// Auth wraps every admin endpoint. If nil, the endpoints are unauthenticated and the router
+	// must only be mounted on an internal listener.
+	Auth AuthFunc
+	// LogLevel is the filter that log output is written through, installed with log.SetDestination.
+	// If nil, the log level endpoint is not registered.
+	LogLevel *LevelFilter
+}
+
+type admin struct {
+	cfg       Config
+	responder *responder.Responder
+}
+
+// New creates a router serving the admin endpoints: pprof profiles, runtime stats, build info,
+// the server's middleware chain, config and connection stats, and a dynamic log level. The router is intended to
+// be served on a separate listener, e.g. using (*dphttp.Server).AddAddress.
+func New(cfg Config) *mux.Router {
+	a := &admin{cfg: cfg, responder: responder.New()}
+	r := mux.NewRouter()
+
+	r.Path(PprofPath + "cmdline").Handler(a.auth(pprof.Cmdline))
+	r.Path(PprofPath + "profile").Handler(a.auth(pprof.Profile))
+	r.Path(PprofPath + "symbol").Handler(a.auth(pprof.Symbol))
+	r.Path(PprofPath + "trace").Handler(a.auth(pprof.Trace))
+	// the index also serves the named profiles, e.g. /debug/pprof/heap
+	r.PathPrefix(PprofPath).Handler(a.auth(pprof.Index))
+
+	r.Path(RuntimePath).Methods(http.MethodGet).Handler(a.auth(a.runtime))
+	r.Path(BuildInfoPath).Methods(http.MethodGet).Handler(a.auth(a.buildInfo))
+	if cfg.Server != nil {
+		r.Path(MiddlewarePath).Methods(http.MethodGet).Handler(a.auth(a.middleware))
+		r.Path(ConfigPath).Methods(http.MethodGet).Handler(a.auth(a.config))
+		r.Path(ConnectionsPath).Methods(http.MethodGet).Handler(a.auth(a.connections))
+	}
+	if cfg.LogLevel != nil {
+		r.Path(LogLevelPath).Methods(http.MethodGet).Handler(a.auth(a.getLogLevel))
+		r.Path(LogLevelPath).Methods(http.MethodPut).Handler(a.auth(a.setLogLevel))
+	}
+
+	return r
+}
+
+func (a *admin) auth(h func(http.ResponseWriter, *http.Request)) http.Handler {
+	if a.cfg.Auth == nil {
+		return http.HandlerFunc(h)
+	}
+	return a.cfg.Auth(h)
+}
+
+// RuntimeStats is the response body of the runtime endpoint
+type RuntimeStats struct {
+	GoVersion    string      `json:"go_version"`
+	Uptime       string      `json:"uptime"`
+	NumCPU       int         `json:"num_cpu"`
+	GOMAXPROCS   int         `json:"gomaxprocs"`
+	NumGoroutine int         `json:"num_goroutine"`
+	Memory       MemoryStats `json:"memory"`
+}
+
+// MemoryStats is a summary of runtime.MemStats
+type MemoryStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+func (a *admin) runtime(w http.ResponseWriter, req *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	a.responder.JSON(req.Context(), w, http.StatusOK, RuntimeStats{
+		GoVersion:    runtime.Version(),
+		Uptime:       time.Since(started).Round(time.Second).String(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumGoroutine: runtime.NumGoroutine(),
+		Memory: MemoryStats{
+			Alloc:        m.Alloc,
+			TotalAlloc:   m.TotalAlloc,
+			Sys:          m.Sys,
+			HeapAlloc:    m.HeapAlloc,
+			HeapInuse:    m.HeapInuse,
+			HeapObjects:  m.HeapObjects,
+			NumGC:        m.NumGC,
+			PauseTotalNs: m.PauseTotalNs,
+		},
+	})
+}
+
+// BuildInfo is the response body of the build info endpoint
+type BuildInfo struct {
+	GoVersion    string            `json:"go_version"`
+	Path         string            `json:"path"`
+	Version      string            `json:"version"`
+	Settings     map[string]string `json:"settings"`
+	Dependencies []Module          `json:"dependencies"`
+}
+
+// Module is a module dependency in BuildInfo
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+var readBuildInfo = debug.ReadBuildInfo
+
+func (a *admin) buildInfo(w http.ResponseWriter, req *http.Request) {
+	info, ok := readBuildInfo()
+	if !ok {
+		a.responder.Error(req.Context(), w, http.StatusNotFound, ErrBuildInfoUnavailable)
+		return
+	}
+
+	resp := BuildInfo{
+		GoVersion:    info.GoVersion,
+		Path:         info.Main.Path,
+		Version:      info.Main.Version,
+		Settings:     make(map[string]string, len(info.Settings)),
+		Dependencies: make([]Module, 0, len(info.Deps)),
+	}
+	for _, s := range info.Settings {
+		resp.Settings[s.Key] = s.Value
+	}
+	for _, d := range info.Deps {
+		if d.Replace != nil {
+			d = d.Replace
+		}
+		resp.Dependencies = append(resp.Dependencies, Module{Path: d.Path, Version: d.Version})
+	}
+
+	a.responder.JSON(req.Context(), w, http.StatusOK, resp)
+}
+
+// MiddlewareChain is the response body of the middleware endpoint
+type MiddlewareChain struct {
+	Middleware []string `json:"middleware"`
+}
+
+func (a *admin) middleware(w http.ResponseWriter, req *http.Request) {
+	a.responder.JSON(req.Context(), w, http.StatusOK, MiddlewareChain{Middleware: a.cfg.Server.Middleware()})
+}
+
+// ServerConfig is the response body of the config endpoint. Durations are formatted as strings.
+type ServerConfig struct {
+	Addr                   string                `json:"addr"`
+	TLS                    bool                  `json:"tls"`
+	ReadTimeout            string                `json:"read_timeout"`
+	ReadHeaderTimeout      string                `json:"read_header_timeout"`
+	WriteTimeout           string                `json:"write_timeout"`
+	IdleTimeout            string                `json:"idle_timeout"`
+	MaxHeaderBytes         int                   `json:"max_header_bytes"`
+	MaxConnections         int                   `json:"max_connections"`
+	RequestTimeout         string                `json:"request_timeout"`
+	DefaultShutdownTimeout string                `json:"default_shutdown_timeout"`
+	HandleOSSignals        bool                  `json:"handle_os_signals"`
+	Middleware             []string              `json:"middleware"`
+	Listeners              []dphttp.ListenerInfo `json:"listeners"`
+}
+
+func (a *admin) config(w http.ResponseWriter, req *http.Request) {
+	s := a.cfg.Server
+	a.responder.JSON(req.Context(), w, http.StatusOK, ServerConfig{
+		Addr:                   s.Addr,
+		TLS:                    s.CertFile != "" && s.KeyFile != "",
+		ReadTimeout:            s.ReadTimeout.String(),
+		ReadHeaderTimeout:      s.ReadHeaderTimeout.String(),
+		WriteTimeout:           s.WriteTimeout.String(),
+		IdleTimeout:            s.IdleTimeout.String(),
+		MaxHeaderBytes:         s.MaxHeaderBytes,
+		MaxConnections:         s.MaxConnections,
+		RequestTimeout:         s.RequestTimeout.String(),
+		DefaultShutdownTimeout: s.DefaultShutdownTimeout.String(),
+		HandleOSSignals:        s.HandleOSSignals,
+		Middleware:             s.Middleware(),
+		Listeners:              s.Listeners(),
+	})
+}
+
+func (a *admin) connections(w http.ResponseWriter, req *http.Request) {
+	a.responder.JSON(req.Context(), w, http.StatusOK, a.cfg.Server.ConnStats())
+}
+
+// LogLevel is the request and response body of the log level endpoint
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+func (a *admin) getLogLevel(w http.ResponseWriter, req *http.Request) {
+	a.responder.JSON(req.Context(), w, http.StatusOK, LogLevel{Level: a.cfg.LogLevel.Level()})
+}
+
+func (a *admin) setLogLevel(w http.ResponseWriter, req *http.Request) {
+	defer dphttp.DrainBody(req)
+
+	var body LogLevel
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		a.responder.Error(req.Context(), w, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %w", err))
+		return
+	}
+	if err := a.cfg.LogLevel.SetLevel(body.Level); err != nil {
+		a.responder.Error(req.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+
+	a.responder.JSON(req.Context(), w, http.StatusOK, LogLevel{Level: a.cfg.LogLevel.Level()})
+}
@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "http://localhost:8081"+path, strings.NewReader(body)))
+	return w
+}
+
+func TestAdminRouter(t *testing.T) {
+	Convey("Given an admin router for a server with additional middleware and listeners", t, func() {
+		server := dphttp.NewServer(":8080", http.NotFoundHandler())
+		server.AddMiddleware("CORS", func(h http.Handler) http.Handler { return h })
+		server.AddUnixSocket("/tmp/test.sock", nil)
+		filter := NewLevelFilter(&bytes.Buffer{})
+		router := New(Config{Server: server, LogLevel: filter})
+
+		Convey("When the middleware endpoint is requested", func() {
+			w := serve(router, http.MethodGet, MiddlewarePath, "")
+
+			Convey("Then the middleware chain is listed in order", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var body MiddlewareChain
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Middleware, ShouldResemble, []string{dphttp.RequestIDHandlerKey, dphttp.LogHandlerKey, "CORS"})
+			})
+		})
+
+		Convey("When the config endpoint is requested", func() {
+			w := serve(router, http.MethodGet, ConfigPath, "")
+
+			Convey("Then the server config is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var body ServerConfig
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Addr, ShouldEqual, ":8080")
+				So(body.WriteTimeout, ShouldEqual, "10s")
+				So(body.TLS, ShouldBeFalse)
+				So(body.Listeners, ShouldResemble, []dphttp.ListenerInfo{{Network: dphttp.NetworkUnix, Address: "/tmp/test.sock"}})
+			})
+		})
+
+		Convey("When the connections endpoint is requested", func() {
+			w := serve(router, http.MethodGet, ConnectionsPath, "")
+
+			Convey("Then the server's connection stats are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var body dphttp.ConnStats
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, server.ConnStats())
+			})
+		})
+
+		Convey("When the runtime endpoint is requested", func() {
+			w := serve(router, http.MethodGet, RuntimePath, "")
+
+			Convey("Then runtime stats are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var body RuntimeStats
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.NumGoroutine, ShouldBeGreaterThan, 0)
+				So(body.Memory.Sys, ShouldBeGreaterThan, 0)
+			})
+		})
+
+		Convey("When the build info endpoint is requested", func() {
+			w := serve(router, http.MethodGet, BuildInfoPath, "")
+
+			Convey("Then the build info is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var body BuildInfo
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.GoVersion, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When build info is unavailable", func() {
+			readBuildInfo = func() (*debug.BuildInfo, bool) { return nil, false }
+			w := serve(router, http.MethodGet, BuildInfoPath, "")
+
+			Convey("Then a 404 is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(w.Body.String(), ShouldContainSubstring, ErrBuildInfoUnavailable.Error())
+			})
+
+			Reset(func() {
+				readBuildInfo = debug.ReadBuildInfo
+			})
+		})
+
+		Convey("When a pprof profile is requested", func() {
+			w := serve(router, http.MethodGet, PprofPath+"goroutine?debug=1", "")
+
+			Convey("Then the profile is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldContainSubstring, "goroutine profile")
+			})
+		})
+
+		Convey("When the pprof cmdline is requested", func() {
+			w := serve(router, http.MethodGet, PprofPath+"cmdline", "")
+
+			Convey("Then the command line is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When the log level is set", func() {
+			w := serve(router, http.MethodPut, LogLevelPath, `{"level":"warn"}`)
+
+			Convey("Then the new level is applied and returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, `{"level":"WARN"}`)
+				So(filter.Level(), ShouldEqual, LevelWarn)
+
+				w = serve(router, http.MethodGet, LogLevelPath, "")
+				So(w.Body.String(), ShouldEqual, `{"level":"WARN"}`)
+			})
+		})
+
+		Convey("When an invalid log level is set", func() {
+			w := serve(router, http.MethodPut, LogLevelPath, `{"level":"verbose"}`)
+
+			Convey("Then a 400 is returned and the level is unchanged", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, ErrInvalidLogLevel.Error())
+				So(filter.Level(), ShouldEqual, LevelInfo)
+			})
+		})
+	})
+
+	Convey("Given an admin router with an auth check", t, func() {
+		router := New(Config{
+			Auth: func(handle func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
+				return func(w http.ResponseWriter, req *http.Request) {
+					if req.Header.Get("Authorization") == "" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					handle(w, req)
+				}
+			},
+		})
+
+		Convey("When an unauthorised request is made", func() {
+			w := serve(router, http.MethodGet, RuntimePath, "")
+
+			Convey("Then it is rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("When a pprof profile is requested without authorisation", func() {
+			w := serve(router, http.MethodGet, PprofPath+"heap", "")
+
+			Convey("Then it is rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("When the server endpoints are requested without a server configured", func() {
+			w := serve(router, http.MethodGet, ConfigPath, "")
+
+			Convey("Then they are not found", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func TestLevelFilter(t *testing.T) {
+	Convey("Given a level filter set to WARN", t, func() {
+		buf := &bytes.Buffer{}
+		filter := NewLevelFilter(buf)
+		So(filter.SetLevel(LevelWarn), ShouldBeNil)
+
+		Convey("When an INFO event is written", func() {
+			event := []byte(`{"severity":3,"event":"info"}` + "\n")
+			n, err := filter.Write(event)
+
+			Convey("Then it is dropped but reported as written", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(event))
+				So(buf.Len(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When an ERROR event is written", func() {
+			_, err := filter.Write([]byte(`{"severity":1,"event":"error"}` + "\n"))
+
+			Convey("Then it is written to the destination", func() {
+				So(err, ShouldBeNil)
+				So(buf.String(), ShouldContainSubstring, `"event":"error"`)
+			})
+		})
+
+		Convey("When output that is not a JSON event is written", func() {
+			_, err := filter.Write([]byte("human readable event\n"))
+
+			Convey("Then it is written to the destination", func() {
+				So(err, ShouldBeNil)
+				So(buf.String(), ShouldEqual, "human readable event\n")
+			})
+		})
+	})
+}
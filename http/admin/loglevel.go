@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// Log levels, matching the severities used by log.go
+const (
+	LevelFatal = "FATAL"
+	LevelError = "ERROR"
+	LevelWarn  = "WARN"
+	LevelInfo  = "INFO"
+)
+
+var levels = []string{LevelFatal, LevelError, LevelWarn, LevelInfo}
+
+// ErrInvalidLogLevel is returned when an unknown log level is provided
+var ErrInvalidLogLevel = errors.New("invalid log level, must be one of FATAL, ERROR, WARN or INFO")
+
+// LevelFilter is an io.Writer that drops log events less severe than the current level before
+// writing them to the destination. It is installed with log.SetDestination, as log.go has no
+// native support for log levels. Events that cannot be parsed are always written.
+type LevelFilter struct {
+	dest  io.Writer
+	level atomic.Int32
+}
+
+// NewLevelFilter creates a LevelFilter writing to dest, with the level set to INFO so that all
+// events are written
+func NewLevelFilter(dest io.Writer) *LevelFilter {
+	f := &LevelFilter{dest: dest}
+	f.level.Store(int32(len(levels) - 1))
+	return f
+}
+
+// Level returns the name of the current log level
+func (f *LevelFilter) Level() string {
+	return levels[f.level.Load()]
+}
+
+// SetLevel sets the current log level by name. Names are case insensitive.
+func (f *LevelFilter) SetLevel(level string) error {
+	for i, l := range levels {
+		if strings.EqualFold(level, l) {
+			f.level.Store(int32(i))
+			return nil
+		}
+	}
+	return ErrInvalidLogLevel
+}
+
+// Write writes p to the destination unless it is an event less severe than the current level.
+// Dropped events are reported as written so that log.go does not use its fallback destination.
+func (f *LevelFilter) Write(p []byte) (int, error) {
+	var event struct {
+		Severity *int32 `json:"severity"`
+	}
+	if err := json.Unmarshal(p, &event); err == nil && event.Severity != nil && *event.Severity > f.level.Load() {
+		return len(p), nil
+	}
+	return f.dest.Write(p)
+}
@@ -25,12 +25,17 @@ const (
 
 // Client is an extension of the net/http client with ability to add
 // timeouts, exponential backoff and context-based cancellation.
+//
+// If RequestCompression is set to one of EncodingGzip, EncodingDeflate or
+// EncodingZstd, request bodies are compressed with that encoding before
+// being sent.
 type Client struct {
 	MaxRetries         int
 	RetryTime          time.Duration
 	PathsWithNoRetries map[string]bool
 	HTTPClient         *http.Client
 	TotalTimeout       time.Duration
+	RequestCompression string
 }
 
 // DefaultTransport is the default implementation of Transport and is
@@ -210,6 +215,10 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 	}
 	request.AddRequestIdHeader(req, upstreamCorrelationIDs+request.NewRequestID(addedIDLen))
 
+	if err := compressRequestBody(req, c.RequestCompression); err != nil {
+		return nil, err
+	}
+
 	doer := func(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
 		if req.ContentLength > 0 {
 			var err error
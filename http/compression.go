@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Request compression encodings supported by Client.RequestCompression
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingZstd    = "zstd"
+
+	ContentEncodingHeader = "Content-Encoding"
+)
+
+// ErrUnsupportedEncoding is returned when a client is configured with an unsupported request compression encoding
+var ErrUnsupportedEncoding = errors.New("unsupported request compression encoding")
+
+// compressRequestBody replaces the body of the request with a copy compressed with the provided encoding.
+// Requests without a body, or whose body is already encoded, are left unchanged.
+func compressRequestBody(req *http.Request, encoding string) error {
+	if encoding == "" || req.Body == nil || req.Body == http.NoBody || req.Header.Get(ContentEncodingHeader) != "" {
+		return nil
+	}
+
+	body := req.Body
+	if req.GetBody != nil {
+		var err error
+		if body, err = req.GetBody(); err != nil {
+			return err
+		}
+	}
+	defer body.Close()
+
+	buf := &bytes.Buffer{}
+	w, err := newEncoder(buf, encoding)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, body); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	compressed := buf.Bytes()
+	req.Header.Set(ContentEncodingHeader, encoding)
+	req.ContentLength = int64(len(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+func newEncoder(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	case EncodingDeflate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	case EncodingZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompressRequestBody(t *testing.T) {
+	body := strings.Repeat(`{"dimension":"aggregate","option":"cpih1dim1A0"}`, 100)
+
+	decoders := map[string]func(io.Reader) io.Reader{
+		EncodingGzip: func(r io.Reader) io.Reader {
+			gr, err := gzip.NewReader(r)
+			So(err, ShouldBeNil)
+			return gr
+		},
+		EncodingDeflate: func(r io.Reader) io.Reader {
+			return flate.NewReader(r)
+		},
+		EncodingZstd: func(r io.Reader) io.Reader {
+			zr, err := zstd.NewReader(r)
+			So(err, ShouldBeNil)
+			return zr
+		},
+	}
+
+	for encoding, decode := range decoders {
+		Convey("Given a request with a body and the "+encoding+" encoding", t, func() {
+			req, err := http.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+			So(err, ShouldBeNil)
+
+			Convey("When compressRequestBody is called", func() {
+				err := compressRequestBody(req, encoding)
+				So(err, ShouldBeNil)
+
+				Convey("Then the body is compressed and the headers updated", func() {
+					So(req.Header.Get(ContentEncodingHeader), ShouldEqual, encoding)
+					So(req.ContentLength, ShouldBeLessThan, len(body))
+
+					b, err := io.ReadAll(decode(req.Body))
+					So(err, ShouldBeNil)
+					So(string(b), ShouldEqual, body)
+				})
+
+				Convey("And GetBody returns a fresh copy of the compressed body", func() {
+					rc, err := req.GetBody()
+					So(err, ShouldBeNil)
+					b, err := io.ReadAll(rc)
+					So(err, ShouldBeNil)
+					So(int64(len(b)), ShouldEqual, req.ContentLength)
+				})
+			})
+		})
+	}
+
+	Convey("Given a request with a body that is already encoded", t, func() {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+		So(err, ShouldBeNil)
+		req.Header.Set(ContentEncodingHeader, "br")
+
+		Convey("Then the body is left unchanged", func() {
+			So(compressRequestBody(req, EncodingGzip), ShouldBeNil)
+			So(req.Header.Get(ContentEncodingHeader), ShouldEqual, "br")
+			So(req.ContentLength, ShouldEqual, len(body))
+		})
+	})
+
+	Convey("Given an unsupported encoding", t, func() {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+		So(err, ShouldBeNil)
+
+		Convey("Then an error is returned", func() {
+			So(compressRequestBody(req, "compress"), ShouldEqual, ErrUnsupportedEncoding)
+		})
+	})
+}
+
+func TestClientRequestCompression(t *testing.T) {
+	Convey("Given a client configured with gzip request compression", t, func() {
+		var received []byte
+		var receivedEncoding string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedEncoding = r.Header.Get(ContentEncodingHeader)
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received, _ = io.ReadAll(gr)
+		}))
+		defer ts.Close()
+
+		client := NewClient().(*Client)
+		client.RequestCompression = EncodingGzip
+
+		Convey("When Post() is called", func() {
+			resp, err := client.Post(context.Background(), ts.URL, "text/csv", bytes.NewBufferString("a,b,c\n1,2,3"))
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+
+			Convey("Then the server receives a gzip-encoded body", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(receivedEncoding, ShouldEqual, EncodingGzip)
+				So(string(received), ShouldEqual, "a,b,c\n1,2,3")
+			})
+		})
+	})
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnStats is a snapshot of the connections handled by a Server, across all of its listeners.
+// New, Active and Idle are the numbers of connections currently in each state; Accepted, Hijacked
+// and Closed are totals since the server started.
+type ConnStats struct {
+	New      int64  `json:"new"`
+	Active   int64  `json:"active"`
+	Idle     int64  `json:"idle"`
+	Open     int64  `json:"open"`
+	Accepted uint64 `json:"accepted"`
+	Hijacked uint64 `json:"hijacked"`
+	Closed   uint64 `json:"closed"`
+}
+
+// connTracker tracks the state of each open connection using the http.Server ConnState hook
+type connTracker struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+	stats  ConnStats
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{states: make(map[net.Conn]http.ConnState)}
+}
+
+// hook returns a ConnState hook that tracks connection state before calling next, if set
+func (t *connTracker) hook(next func(net.Conn, http.ConnState)) func(net.Conn, http.ConnState) {
+	return func(c net.Conn, state http.ConnState) {
+		t.track(c, state)
+		if next != nil {
+			next(c, state)
+		}
+	}
+}
+
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.states[c]; ok {
+		t.add(prev, -1)
+	}
+
+	switch state {
+	case http.StateNew:
+		t.stats.Accepted++
+	case http.StateHijacked:
+		t.stats.Hijacked++
+		delete(t.states, c)
+		return
+	case http.StateClosed:
+		t.stats.Closed++
+		delete(t.states, c)
+		return
+	}
+
+	t.states[c] = state
+	t.add(state, 1)
+}
+
+func (t *connTracker) add(state http.ConnState, n int64) {
+	switch state {
+	case http.StateNew:
+		t.stats.New += n
+	case http.StateActive:
+		t.stats.Active += n
+	case http.StateIdle:
+		t.stats.Idle += n
+	}
+	t.stats.Open += n
+}
+
+func (t *connTracker) snapshot() ConnStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// ConnStats returns a snapshot of the connections currently handled by the server
+func (s *Server) ConnStats() ConnStats {
+	if s.conns == nil {
+		return ConnStats{}
+	}
+	return s.conns.snapshot()
+}
+
+// LimitListener returns a net.Listener that accepts at most n simultaneous connections from ln.
+// Accept blocks once the limit is reached until an accepted connection is closed.
+func LimitListener(ln net.Listener, n int) net.Listener {
+	return &limitListener{Listener: ln, sem: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: c, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
+// wrapListener applies the server's connection limit and ListenerWrapper, if set, to ln
+func (s *Server) wrapListener(ln net.Listener) net.Listener {
+	if s.MaxConnections > 0 {
+		ln = LimitListener(ln, s.MaxConnections)
+	}
+	if s.ListenerWrapper != nil {
+		ln = s.ListenerWrapper(ln)
+	}
+	return ln
+}
+
+func (s *Server) wrapsListeners() bool {
+	return s.MaxConnections > 0 || s.ListenerWrapper != nil
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConnTracker(t *testing.T) {
+	Convey("Given a connection tracker", t, func() {
+		tracker := newConnTracker()
+		var hooked []http.ConnState
+		hook := tracker.hook(func(_ net.Conn, state http.ConnState) {
+			hooked = append(hooked, state)
+		})
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		Convey("When connections change state", func() {
+			hook(c1, http.StateNew)
+			hook(c2, http.StateNew)
+			hook(c1, http.StateActive)
+			hook(c1, http.StateIdle)
+			hook(c2, http.StateActive)
+
+			Convey("Then the current state of each connection is counted", func() {
+				So(tracker.snapshot(), ShouldResemble, ConnStats{Active: 1, Idle: 1, Open: 2, Accepted: 2})
+			})
+
+			Convey("Then the next ConnState hook is called", func() {
+				So(hooked, ShouldResemble, []http.ConnState{http.StateNew, http.StateNew, http.StateActive, http.StateIdle, http.StateActive})
+			})
+
+			Convey("And connections are hijacked or closed", func() {
+				hook(c1, http.StateClosed)
+				hook(c2, http.StateHijacked)
+
+				Convey("Then they are no longer open", func() {
+					So(tracker.snapshot(), ShouldResemble, ConnStats{Accepted: 2, Hijacked: 1, Closed: 1})
+				})
+			})
+		})
+	})
+}
+
+func TestLimitListener(t *testing.T) {
+	Convey("Given a listener limited to 1 connection", t, func() {
+		base, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		ln := LimitListener(base, 1)
+		defer ln.Close()
+
+		accepted := make(chan net.Conn, 2)
+		go func() {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				accepted <- c
+			}
+		}()
+
+		d1, err := net.Dial(NetworkTCP, base.Addr().String())
+		So(err, ShouldBeNil)
+		defer d1.Close()
+		c1 := <-accepted
+
+		Convey("When a second connection is made", func() {
+			d2, err := net.Dial(NetworkTCP, base.Addr().String())
+			So(err, ShouldBeNil)
+			defer d2.Close()
+
+			Convey("Then it is not accepted until the first is closed", func() {
+				select {
+				case <-accepted:
+					t.Fatal("second connection accepted while at the limit")
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				So(c1.Close(), ShouldBeNil)
+				select {
+				case c2 := <-accepted:
+					c2.Close()
+				case <-time.After(time.Second):
+					t.Fatal("second connection not accepted after the first was closed")
+				}
+			})
+		})
+
+		Convey("When the listener is closed while at the limit", func() {
+			So(ln.Close(), ShouldBeNil)
+
+			Convey("Then Accept returns", func() {
+				_, err := ln.Accept()
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestServerConnStats(t *testing.T) {
+	doListenAndServe = func(httpServer *Server) error {
+		if httpServer.mainListener != nil {
+			return timeoutHandler(httpServer).Serve(httpServer.mainListener)
+		}
+		return timeoutHandler(httpServer).ListenAndServe()
+	}
+	doShutdown = func(ctx context.Context, httpServer *http.Server) error {
+		return httpServer.Shutdown(ctx)
+	}
+
+	Convey("Given a running server with a connection limit and a listener wrapper", t, func() {
+		ln, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		url := "http://" + ln.Addr().String()
+
+		wrapped := false
+		s := NewServer("", textHandler("ok"))
+		s.HandleOSSignals = false
+		s.MaxConnections = 1
+		s.ListenerWrapper = func(ln net.Listener) net.Listener {
+			wrapped = true
+			return ln
+		}
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.ServeListener(ln)
+		}()
+
+		Convey("When a keep-alive connection has completed a request", func() {
+			client := &http.Client{Transport: &http.Transport{}}
+			body, err := getBody(client, url)
+			So(err, ShouldBeNil)
+			So(body, ShouldEqual, "ok")
+
+			Convey("Then it is counted as idle", func() {
+				So(wrapped, ShouldBeTrue)
+				So(waitForConnStats(s, func(c ConnStats) bool { return c.Idle == 1 }), ShouldResemble, ConnStats{Idle: 1, Open: 1, Accepted: 1})
+			})
+
+			Convey("Then another connection waits for it to close", func() {
+				other := &http.Client{Transport: &http.Transport{}, Timeout: 100 * time.Millisecond}
+				_, err := getBody(other, url)
+				So(err, ShouldNotBeNil)
+
+				client.CloseIdleConnections()
+				other.Timeout = time.Second
+				body, err := getBody(other, url)
+				So(err, ShouldBeNil)
+				So(body, ShouldEqual, "ok")
+				So(s.ConnStats().Closed, ShouldBeGreaterThanOrEqualTo, 1)
+			})
+		})
+
+		Reset(func() {
+			So(s.Shutdown(context.Background()), ShouldBeNil)
+			So(<-errs, ShouldEqual, http.ErrServerClosed)
+		})
+	})
+}
+
+// waitForConnStats polls the server's connection stats until cond is met, as ConnState hooks
+// are called asynchronously to the client receiving a response
+func waitForConnStats(s *Server, cond func(ConnStats) bool) ConnStats {
+	stats := s.ConnStats()
+	for i := 0; i < 100 && !cond(stats); i++ {
+		time.Sleep(5 * time.Millisecond)
+		stats = s.ConnStats()
+	}
+	return stats
+}
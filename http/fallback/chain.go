@@ -1,16 +1,51 @@
 package fallback
 
 import (
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
+	"slices"
 )
 
+// DefaultAttemptsHeader is the suggested response header for recording fallback attempts
+const DefaultAttemptsHeader = "X-Fallback-Attempts"
+
+// Condition is a predicate on a handler's response status and headers that triggers a fallback
+type Condition func(status int, header http.Header) bool
+
+// StatusIs returns a Condition that matches any of the provided statuses
+func StatusIs(statuses ...int) Condition {
+	return func(status int, _ http.Header) bool {
+		return slices.Contains(statuses, status)
+	}
+}
+
+// StatusInRange returns a Condition that matches statuses from minStatus to maxStatus inclusive,
+// e.g. StatusInRange(500, 599) for any server error
+func StatusInRange(minStatus, maxStatus int) Condition {
+	return func(status int, _ http.Header) bool {
+		return status >= minStatus && status <= maxStatus
+	}
+}
+
+// HeaderIs returns a Condition that matches when the response header has the provided value
+func HeaderIs(name, value string) Condition {
+	return func(_ int, header http.Header) bool {
+		return slices.Contains(header.Values(name), value)
+	}
+}
+
 // AlternativeBuilder is a struct that helps to build a fallback handler by the use of a narrative structure
 // for example using the following:
 //
 //	fallback.Try(handler1).WhenStatus(http.StatusNotFound).Then(handler2)
+//
+// Multiple conditions may be provided for each fallback, any of which will trigger it.
 type AlternativeBuilder struct {
-	tryHandler http.Handler
-	whenStatus *int
+	alternative *Alternative
+	tryHandler  http.Handler
+	conditions  []Condition
 }
 
 // Try takes a `http.Handler` and returns an incomplete AlternativeBuilder
@@ -18,33 +53,91 @@ func Try(h http.Handler) *AlternativeBuilder {
 	return &AlternativeBuilder{tryHandler: h}
 }
 
+// When extends an AlternativeBuilder and includes a custom condition on the http response
+func (ab *AlternativeBuilder) When(condition Condition) *AlternativeBuilder {
+	ab.conditions = append(ab.conditions, condition)
+	return ab
+}
+
 // WhenStatus extends an AlternativeBuilder and includes a condition on the http status
 func (ab *AlternativeBuilder) WhenStatus(status int) *AlternativeBuilder {
-	ab.whenStatus = &status
-	return ab
+	return ab.When(StatusIs(status))
+}
+
+// WhenStatusIn extends an AlternativeBuilder and includes a condition on the http status being any of those provided
+func (ab *AlternativeBuilder) WhenStatusIn(statuses ...int) *AlternativeBuilder {
+	return ab.When(StatusIs(statuses...))
 }
 
-// Then takes an AlternativeBuilder and returns a complete Alternative with the fallback handler provided
+// WhenStatusInRange extends an AlternativeBuilder and includes a condition on the http status being within a range
+func (ab *AlternativeBuilder) WhenStatusInRange(minStatus, maxStatus int) *AlternativeBuilder {
+	return ab.When(StatusInRange(minStatus, maxStatus))
+}
+
+// WhenHeader extends an AlternativeBuilder and includes a condition on a http response header value
+func (ab *AlternativeBuilder) WhenHeader(name, value string) *AlternativeBuilder {
+	return ab.When(HeaderIs(name, value))
+}
+
+// Then takes an AlternativeBuilder and returns a complete Alternative with the fallback handler provided. If the
+// builder extends an existing Alternative, a new Alternative is returned with the fallback added to the end of its
+// chain, leaving the existing Alternative unchanged.
 func (ab *AlternativeBuilder) Then(handler http.Handler) *Alternative {
-	return &Alternative{
-		TryHandler:   ab.tryHandler,
-		WhenStatusIs: ab.whenStatus,
-		ThenHandler:  handler,
+	fb := Fallback{Conditions: ab.conditions, Handler: handler}
+	if ab.alternative == nil {
+		return &Alternative{
+			TryHandler: ab.tryHandler,
+			Fallbacks:  []Fallback{fb},
+		}
 	}
+
+	alternative := *ab.alternative
+	alternative.Fallbacks = append(slices.Clip(alternative.Fallbacks), fb)
+	return &alternative
+}
+
+// Fallback is a handler in an Alternative chain, which is tried when any of its conditions match the previous response
+type Fallback struct {
+	Conditions []Condition
+	Handler    http.Handler
+}
+
+func (fb Fallback) matches(status int, header http.Header) bool {
+	for _, condition := range fb.Conditions {
+		if condition(status, header) {
+			return true
+		}
+	}
+	return false
 }
 
 // Alternative implements a [http.Handler] which wraps another http.Handler and tries serving it first. Depending on the
 // status returned by the first handler it will either return the response to the caller immediately or it will pass a
 // copy of the request to the second handler instead, retuning that handlers response in that case.
+//
+// The decision is made as soon as a handler writes its response headers, so a response that does not trigger a
+// fallback is streamed straight to the caller, including any flushes, rather than being buffered.
+//
+// Fallbacks are evaluated in order against the most recent response, so a fallback is only tried if its conditions
+// match the response of the handler served before it. If AttemptsHeader is set, the index and status of each handler
+// tried are recorded in that response header, e.g. "0=404" and "1=200", with the TryHandler at index 0.
 type Alternative struct {
-	TryHandler   http.Handler
+	TryHandler http.Handler
+	// Deprecated: WhenStatusIs and ThenHandler are tried before Fallbacks, use Fallbacks instead
 	WhenStatusIs *int
-	ThenHandler  http.Handler
+	// Deprecated: use Fallbacks instead
+	ThenHandler    http.Handler
+	Fallbacks      []Fallback
+	AttemptsHeader string
 }
+
+// responseWriter buffers a response. If maxBodySize is set, the body is truncated to that size.
 type responseWriter struct {
-	header     http.Header
-	statusCode int
-	body       []byte
+	header      http.Header
+	statusCode  int
+	body        []byte
+	maxBodySize int
+	truncated   bool
 }
 
 var _ http.ResponseWriter = &responseWriter{}
@@ -59,6 +152,13 @@ func (t *responseWriter) Header() http.Header {
 }
 
 func (t *responseWriter) Write(bytes []byte) (int, error) {
+	if t.statusCode == 0 {
+		t.statusCode = http.StatusOK
+	}
+	if t.maxBodySize > 0 && len(t.body)+len(bytes) > t.maxBodySize {
+		t.truncated = true
+		return len(bytes), nil
+	}
 	t.body = append(t.body, bytes...)
 	return len(bytes), nil
 }
@@ -75,37 +175,174 @@ func (t *responseWriter) StatusCode() int {
 	return t.statusCode
 }
 
-func (alternative *Alternative) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Split the request's body reader so that it can be read by both handlers
-	readClosers := ReadCloserSplit(r.Body, 2)
-	bodyReadCloser1 := readClosers[0]
-	bodyReadCloser2 := readClosers[1]
-	defer bodyReadCloser1.Close() // TODO not sure we even need to do this, will handlers defer close anyway?
-	defer bodyReadCloser2.Close()
-
-	w1 := responseWriter{} // TODO don't slurp response body of first request
-	r1 := r.Clone(r.Context())
-	r1.Body = bodyReadCloser1
-	alternative.TryHandler.ServeHTTP(&w1, r1)
-
-	if alternative.WhenStatusIs == nil || w1.statusCode != *alternative.WhenStatusIs {
-		for k, vs := range w1.header {
-			w.Header()[k] = vs
+// WithAttemptsHeader returns a copy of the Alternative that records each handler tried in the named response header
+func (alternative *Alternative) WithAttemptsHeader(name string) *Alternative {
+	a := *alternative
+	a.AttemptsHeader = name
+	return &a
+}
+
+// fallbacks returns the chain of fallbacks, including the deprecated WhenStatusIs/ThenHandler fallback if set
+func (alternative *Alternative) fallbacks() []Fallback {
+	if alternative.ThenHandler == nil {
+		return alternative.Fallbacks
+	}
+	legacy := Fallback{Handler: alternative.ThenHandler}
+	if alternative.WhenStatusIs != nil {
+		legacy.Conditions = []Condition{StatusIs(*alternative.WhenStatusIs)}
+	}
+	return append([]Fallback{legacy}, alternative.Fallbacks...)
+}
+
+// next returns the index of the next fallback after the one at index from whose conditions match the response, or -1
+func next(fallbacks []Fallback, from, status int, header http.Header) int {
+	for i := from + 1; i < len(fallbacks); i++ {
+		if fallbacks[i].matches(status, header) {
+			return i
 		}
-		w.WriteHeader(w1.statusCode)
-		//nolint:errcheck // TODO handle error
-		w.Write(w1.body)
-		return
 	}
+	return -1
+}
+
+func (alternative *Alternative) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fallbacks := alternative.fallbacks()
+
+	// Split the request's body reader so that it can be read by every handler in the chain
+	readClosers := ReadCloserSplit(r.Body, len(fallbacks)+1)
+	for _, rc := range readClosers {
+		defer rc.Close()
+	}
+
+	sw := &streamingWriter{w: w, fallbacks: fallbacks, index: -1, attemptsHeader: alternative.AttemptsHeader}
+	handler := alternative.TryHandler
+	for handler != nil {
+		req := r.Clone(r.Context())
+		req.Body = readClosers[sw.index+1]
+		handler.ServeHTTP(sw, req)
+
+		handler = sw.finish()
+	}
+}
 
-	r2 := r.Clone(r.Context())
-	r2.Body = bodyReadCloser2
-	alternative.ThenHandler.ServeHTTP(w, r2)
+// When extends an Alternative with a further fallback, triggered by a custom condition on the http response
+func (alternative *Alternative) When(condition Condition) *AlternativeBuilder {
+	return (&AlternativeBuilder{alternative: alternative}).When(condition)
 }
 
+// WhenStatus extends an Alternative with a further fallback, triggered by a condition on the http status
 func (alternative *Alternative) WhenStatus(status int) *AlternativeBuilder {
-	return &AlternativeBuilder{
-		tryHandler: alternative,
-		whenStatus: &status,
+	return alternative.When(StatusIs(status))
+}
+
+// WhenStatusIn extends an Alternative with a further fallback, triggered by the http status being any of those provided
+func (alternative *Alternative) WhenStatusIn(statuses ...int) *AlternativeBuilder {
+	return alternative.When(StatusIs(statuses...))
+}
+
+// WhenStatusInRange extends an Alternative with a further fallback, triggered by the http status being within a range
+func (alternative *Alternative) WhenStatusInRange(minStatus, maxStatus int) *AlternativeBuilder {
+	return alternative.When(StatusInRange(minStatus, maxStatus))
+}
+
+// WhenHeader extends an Alternative with a further fallback, triggered by a http response header value
+func (alternative *Alternative) WhenHeader(name, value string) *AlternativeBuilder {
+	return alternative.When(HeaderIs(name, value))
+}
+
+// streamingWriter is the http.ResponseWriter passed to each handler in an Alternative chain. The decision to fall back
+// is made as soon as the handler writes its headers: if the response matches a later fallback's conditions it is
+// discarded, otherwise it is streamed straight through to the underlying http.ResponseWriter.
+type streamingWriter struct {
+	w              http.ResponseWriter
+	fallbacks      []Fallback
+	attemptsHeader string
+	attempts       []string
+
+	// index is the index in fallbacks of the current handler, or -1 for the TryHandler
+	index   int
+	header  http.Header
+	decided bool
+	discard bool
+	next    int
+}
+
+var _ http.ResponseWriter = &streamingWriter{}
+
+func (sw *streamingWriter) Header() http.Header {
+	if sw.header == nil {
+		sw.header = make(http.Header)
+	}
+	return sw.header
+}
+
+func (sw *streamingWriter) WriteHeader(status int) {
+	if sw.decided {
+		return
+	}
+	sw.decided = true
+	sw.attempts = append(sw.attempts, fmt.Sprintf("%d=%d", sw.index+1, status))
+
+	if sw.next = next(sw.fallbacks, sw.index, status, sw.header); sw.next >= 0 {
+		sw.discard = true
+		return
+	}
+
+	for k, vs := range sw.header {
+		sw.w.Header()[k] = vs
+	}
+	if sw.attemptsHeader != "" {
+		sw.w.Header()[sw.attemptsHeader] = sw.attempts
+	}
+	sw.w.WriteHeader(status)
+}
+
+func (sw *streamingWriter) Write(b []byte) (int, error) {
+	if !sw.decided {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if sw.discard {
+		return len(b), nil
+	}
+	return sw.w.Write(b)
+}
+
+// Flush implements http.Flusher, so that streamed responses are flushed to the client
+func (sw *streamingWriter) Flush() {
+	if !sw.decided {
+		sw.WriteHeader(http.StatusOK)
 	}
+	if f, ok := sw.w.(http.Flusher); ok && !sw.discard {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying http.ResponseWriter does
+func (sw *streamingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := sw.w.(http.Hijacker); ok {
+		sw.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController
+func (sw *streamingWriter) Unwrap() http.ResponseWriter {
+	return sw.w
+}
+
+// finish is called once a handler has returned, and returns the next handler to try or nil if the response has been
+// written
+func (sw *streamingWriter) finish() http.Handler {
+	if !sw.decided {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if !sw.discard {
+		return nil
+	}
+
+	sw.index = sw.next
+	sw.header = nil
+	sw.decided = false
+	sw.discard = false
+	return sw.fallbacks[sw.index].Handler
 }
@@ -1,6 +1,8 @@
 package fallback
 
 import (
+	"bufio"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -73,6 +75,155 @@ func TestAlternativeBuilderChaining(t *testing.T) {
 	})
 }
 
+func TestAlternativeConditions(t *testing.T) {
+	Convey("Given an N-way chain with status set, range and header conditions", t, func() {
+		notFound := generateHandlerWithStatus(http.StatusNotFound, primaryDesignation)
+		unavailable := generateHandlerWithStatus(http.StatusServiceUnavailable, secondaryDesignation)
+		ok := generateHandlerWithStatus(http.StatusOK, tertiaryDesignation)
+
+		Convey("When each handler's response matches the next fallback's conditions", func() {
+			alt := Try(notFound).WhenStatusIn(http.StatusNotFound, http.StatusGone).Then(unavailable).
+				WhenStatusInRange(500, 599).Then(ok).
+				WithAttemptsHeader(DefaultAttemptsHeader)
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the chain is evaluated without nesting and the last response is returned", func() {
+				So(alt.Fallbacks, ShouldHaveLength, 2)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, tertiaryDesignation+" response")
+			})
+
+			Convey("Then each attempt is recorded in the attempts header", func() {
+				So(w.Header().Values(DefaultAttemptsHeader), ShouldResemble, []string{"0=404", "1=503", "2=200"})
+			})
+		})
+
+		Convey("When the first handler's response only matches a later fallback", func() {
+			alt := Try(unavailable).WhenStatus(http.StatusNotFound).Then(notFound).
+				WhenStatusInRange(500, 599).Then(ok).
+				WithAttemptsHeader(DefaultAttemptsHeader)
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the non-matching fallback is skipped", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Values(DefaultAttemptsHeader), ShouldResemble, []string{"0=503", "2=200"})
+			})
+		})
+
+		Convey("When a fallback is triggered by a response header or custom condition", func() {
+			alt := Try(notFound).WhenHeader(testHeader, primaryDesignation).Then(unavailable).
+				When(func(status int, header http.Header) bool {
+					return status >= 500 && header.Get(testHeader) == secondaryDesignation
+				}).Then(ok)
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the fallbacks are tried", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, tertiaryDesignation+" response")
+				So(w.Header().Get(DefaultAttemptsHeader), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When an Alternative is extended", func() {
+			base := Try(notFound).WhenStatus(http.StatusNotFound).Then(unavailable)
+			extended := base.WhenStatus(http.StatusServiceUnavailable).Then(ok)
+
+			Convey("Then the original Alternative is unchanged", func() {
+				So(base.Fallbacks, ShouldHaveLength, 1)
+				So(extended.Fallbacks, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the deprecated Alternative fields are used", func() {
+			status := http.StatusNotFound
+			alt := &Alternative{TryHandler: notFound, WhenStatusIs: &status, ThenHandler: ok}
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the fallback is still tried", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, tertiaryDesignation+" response")
+			})
+		})
+	})
+}
+
+func TestAlternativeStreaming(t *testing.T) {
+	Convey("Given an Alternative whose primary handler streams a response", t, func() {
+		release := make(chan struct{})
+		primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(testHeader, primaryDesignation)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("first chunk\n"))
+			w.(http.Flusher).Flush()
+			<-release
+			w.Write([]byte("second chunk\n"))
+		})
+		alt := Try(primary).WhenStatus(http.StatusNotFound).Then(generateHandlerWithStatus(http.StatusOK, secondaryDesignation))
+		server := httptest.NewServer(alt)
+		defer server.Close()
+
+		Convey("When the response does not match the fallback condition", func() {
+			resp, err := http.Get(server.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			reader := bufio.NewReader(resp.Body)
+
+			Convey("Then flushed data is received before the primary handler completes", func() {
+				So(resp.Header.Get(testHeader), ShouldEqual, primaryDesignation)
+				line, err := reader.ReadString('\n')
+				So(err, ShouldBeNil)
+				So(line, ShouldEqual, "first chunk\n")
+
+				close(release)
+				rest, err := io.ReadAll(reader)
+				So(err, ShouldBeNil)
+				So(string(rest), ShouldEqual, "second chunk\n")
+			})
+		})
+	})
+
+	Convey("Given an Alternative whose primary handler matches the fallback condition", t, func() {
+		primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Primary-Only", "true")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		})
+		alt := Try(primary).WhenStatus(http.StatusNotFound).Then(generateHandlerWithStatus(http.StatusOK, secondaryDesignation))
+
+		Convey("When the request is served", func() {
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the primary response is discarded", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, secondaryDesignation+" response")
+				So(w.Header().Get("X-Primary-Only"), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given an Alternative whose primary handler writes a body without a status", t, func() {
+		primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("implicit ok"))
+		})
+		alt := Try(primary).WhenStatus(http.StatusNotFound).Then(generateHandlerWithStatus(http.StatusOK, secondaryDesignation))
+
+		Convey("When the request is served", func() {
+			w := httptest.NewRecorder()
+			alt.ServeHTTP(w, httptest.NewRequest("GET", "/", http.NoBody))
+
+			Convey("Then the response is passed through with an implicit 200 status", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, "implicit ok")
+			})
+		})
+	})
+}
+
 func TestResponseWriter(t *testing.T) {
 	Convey("Given a responseWriter implementation", t, func() {
 		w := &responseWriter{}
@@ -95,7 +246,9 @@ func TestAlternativeWhenStatus(t *testing.T) {
 		alt := &Alternative{}
 		builder := alt.WhenStatus(http.StatusTeapot)
 		So(builder, ShouldNotBeNil)
-		So(*builder.whenStatus, ShouldEqual, http.StatusTeapot)
+		So(builder.alternative, ShouldEqual, alt)
+		So(builder.conditions, ShouldHaveLength, 1)
+		So(builder.conditions[0](http.StatusTeapot, nil), ShouldBeTrue)
 	})
 }
 
@@ -0,0 +1,145 @@
+package fallback
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DiffKind is the kind of difference between two JSON documents
+type DiffKind string
+
+// Kinds of difference between a primary and shadow JSON document
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// Diff is a structural difference between a primary and shadow JSON document. Path is the dot separated path to the
+// value, with array elements identified by index, e.g. "items.0.title". The root document has an empty path.
+type Diff struct {
+	Path    string      `json:"path"`
+	Kind    DiffKind    `json:"kind"`
+	Primary interface{} `json:"primary,omitempty"`
+	Shadow  interface{} `json:"shadow,omitempty"`
+}
+
+// DiffJSON returns the structural differences between two JSON documents, ordered by path. Values at any path matching
+// one of the ignore patterns are not compared. Patterns are dot separated paths in which "*" matches any single object
+// key or array index, e.g. "links.self.href" or "items.*.last_updated". If either document is not valid JSON, a single
+// DiffChanged at the root is returned when the documents are not byte for byte equal.
+func DiffJSON(primary, shadow []byte, ignore []string) []Diff {
+	var p, s interface{}
+	if json.Unmarshal(primary, &p) != nil || json.Unmarshal(shadow, &s) != nil {
+		if bytes.Equal(primary, shadow) {
+			return nil
+		}
+		return []Diff{{Kind: DiffChanged}}
+	}
+
+	patterns := make([][]string, 0, len(ignore))
+	for _, pattern := range ignore {
+		patterns = append(patterns, strings.Split(pattern, "."))
+	}
+
+	d := &differ{ignore: patterns}
+	d.diff(nil, p, s)
+	return d.diffs
+}
+
+type differ struct {
+	ignore [][]string
+	diffs  []Diff
+}
+
+func (d *differ) diff(path []string, p, s interface{}) {
+	if d.ignored(path) {
+		return
+	}
+
+	switch pv := p.(type) {
+	case map[string]interface{}:
+		sv, ok := s.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(pv)+len(sv))
+		for k := range pv {
+			keys = append(keys, k)
+		}
+		for k := range sv {
+			if _, ok := pv[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			d.member(append(slices.Clip(path), k), pv, sv, k)
+		}
+		return
+	case []interface{}:
+		sv, ok := s.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(pv), len(sv)); i++ {
+			elemPath := append(slices.Clip(path), strconv.Itoa(i))
+			switch {
+			case i >= len(sv):
+				d.add(elemPath, DiffRemoved, pv[i], nil)
+			case i >= len(pv):
+				d.add(elemPath, DiffAdded, nil, sv[i])
+			default:
+				d.diff(elemPath, pv[i], sv[i])
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(p, s) {
+		d.add(path, DiffChanged, p, s)
+	}
+}
+
+func (d *differ) member(path []string, p, s map[string]interface{}, key string) {
+	pv, inPrimary := p[key]
+	sv, inShadow := s[key]
+	switch {
+	case !inShadow:
+		d.add(path, DiffRemoved, pv, nil)
+	case !inPrimary:
+		d.add(path, DiffAdded, nil, sv)
+	default:
+		d.diff(path, pv, sv)
+	}
+}
+
+func (d *differ) add(path []string, kind DiffKind, p, s interface{}) {
+	if d.ignored(path) {
+		return
+	}
+	d.diffs = append(d.diffs, Diff{Path: strings.Join(path, "."), Kind: kind, Primary: p, Shadow: s})
+}
+
+func (d *differ) ignored(path []string) bool {
+	for _, pattern := range d.ignore {
+		if len(pattern) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range pattern {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
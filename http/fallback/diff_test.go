@@ -0,0 +1,67 @@
+package fallback
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffJSON(t *testing.T) {
+	Convey("Given two JSON documents with structural differences", t, func() {
+		primary := []byte(`{"id":"cpih","title":"CPIH","items":[{"id":1,"last_updated":"2024-01-01"},{"id":2}],"links":{"self":{"href":"http://legacy/cpih"}},"removed":true}`)
+		shadow := []byte(`{"id":"cpih","title":"CPIH index","items":[{"id":1,"last_updated":"2024-06-01"},{"id":2},{"id":3}],"links":{"self":{"href":"http://new/cpih"}},"added":null}`)
+
+		Convey("When they are compared", func() {
+			diffs := DiffJSON(primary, shadow, nil)
+
+			Convey("Then every difference is returned, ordered by path", func() {
+				So(diffs, ShouldResemble, []Diff{
+					{Path: "added", Kind: DiffAdded},
+					{Path: "items.0.last_updated", Kind: DiffChanged, Primary: "2024-01-01", Shadow: "2024-06-01"},
+					{Path: "items.2", Kind: DiffAdded, Shadow: map[string]interface{}{"id": float64(3)}},
+					{Path: "links.self.href", Kind: DiffChanged, Primary: "http://legacy/cpih", Shadow: "http://new/cpih"},
+					{Path: "removed", Kind: DiffRemoved, Primary: true},
+					{Path: "title", Kind: DiffChanged, Primary: "CPIH", Shadow: "CPIH index"},
+				})
+			})
+		})
+
+		Convey("When they are compared ignoring fields", func() {
+			diffs := DiffJSON(primary, shadow, []string{"items.*.last_updated", "links", "added", "removed", "items.2"})
+
+			Convey("Then the ignored fields are not compared", func() {
+				So(diffs, ShouldResemble, []Diff{
+					{Path: "title", Kind: DiffChanged, Primary: "CPIH", Shadow: "CPIH index"},
+				})
+			})
+		})
+	})
+
+	Convey("Given two equal JSON documents with different formatting", t, func() {
+		diffs := DiffJSON([]byte(`{"a": [1, 2], "b": {"c": null}}`), []byte(`{"b":{"c":null},"a":[1,2]}`), nil)
+
+		Convey("Then there are no differences", func() {
+			So(diffs, ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given documents with different types at the same path", t, func() {
+		diffs := DiffJSON([]byte(`{"a":{"b":1}}`), []byte(`{"a":[1]}`), nil)
+
+		Convey("Then the value is changed", func() {
+			So(diffs, ShouldResemble, []Diff{
+				{Path: "a", Kind: DiffChanged, Primary: map[string]interface{}{"b": float64(1)}, Shadow: []interface{}{float64(1)}},
+			})
+		})
+	})
+
+	Convey("Given documents that are not JSON", t, func() {
+		Convey("When they are equal", func() {
+			So(DiffJSON([]byte("<html>"), []byte("<html>"), nil), ShouldBeEmpty)
+		})
+
+		Convey("When they differ", func() {
+			So(DiffJSON([]byte("<html>"), []byte(`{}`), nil), ShouldResemble, []Diff{{Kind: DiffChanged}})
+		})
+	})
+}
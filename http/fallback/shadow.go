@@ -0,0 +1,209 @@
+package fallback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// DefaultShadowMaxBodySize is the default maximum size of each response body captured for comparison
+const DefaultShadowMaxBodySize = 1024 * 1024
+
+// DefaultShadowMethods are the methods of the requests replayed to the candidate if ShadowConfig.Methods is empty.
+// Only safe methods are replayed by default, so that writes are not duplicated in the candidate.
+var DefaultShadowMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// ShadowConfig is the configuration of a Shadow handler
+type ShadowConfig struct {
+	// SampleRate is the proportion of requests replayed to the candidate, between 0 and 1. Zero replays no requests,
+	// and 1 replays every request.
+	SampleRate float64
+	// Methods are the methods of the requests that are replayed to the candidate. Empty uses DefaultShadowMethods.
+	// Replaying methods such as POST duplicates the writes they make in the candidate.
+	Methods []string
+	// IgnoreFields are patterns of JSON fields that are not compared, see DiffJSON
+	IgnoreFields []string
+	// MaxBodySize is the maximum size of each response body captured for comparison. Responses are not compared if
+	// either body is larger. Zero uses DefaultShadowMaxBodySize.
+	MaxBodySize int
+	// OnResult is called with the outcome of each replayed request. If nil, the outcome is logged.
+	OnResult func(ShadowResult)
+}
+
+// ShadowResult is the outcome of a request replayed to a shadow handler or upstream
+type ShadowResult struct {
+	Request       *http.Request
+	RequestID     string
+	PrimaryStatus int
+	ShadowStatus  int
+	Duration      time.Duration
+	// Diffs are the structural differences between the primary and shadow JSON responses
+	Diffs []Diff
+	// Truncated is set if either response was too large to compare
+	Truncated bool
+	Err       error
+}
+
+// Matched returns true if the primary and shadow responses had the same status and no differences
+func (result ShadowResult) Matched() bool {
+	return result.Err == nil && !result.Truncated && result.PrimaryStatus == result.ShadowStatus && len(result.Diffs) == 0
+}
+
+// Shadow returns a handler that serves the primary handler's response, and asynchronously replays a sample of requests
+// to the candidate handler, comparing the two responses. The outcome is passed to ShadowConfig.OnResult, or logged.
+// This is intended for comparing a replacement service with a legacy one before migrating traffic to it.
+func Shadow(primary, candidate http.Handler, cfg ShadowConfig) http.Handler {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultShadowMaxBodySize
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = DefaultShadowMethods
+	}
+	return &shadowHandler{primary: primary, candidate: candidate, cfg: cfg}
+}
+
+type shadowHandler struct {
+	primary   http.Handler
+	candidate http.Handler
+	cfg       ShadowConfig
+}
+
+func (s *shadowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.sampled(r) {
+		s.primary.ServeHTTP(w, r)
+		return
+	}
+
+	readClosers := ReadCloserSplit(r.Body, 2)
+	primaryBody, shadowBody := readClosers[0], readClosers[1]
+
+	// the shadow request outlives this handler, so must not be cancelled when the handler returns
+	shadowReq := r.Clone(context.WithoutCancel(r.Context()))
+	shadowReq.Body = shadowBody
+	primaryResult := make(chan *teeRecorder, 1)
+	go func() {
+		defer shadowBody.Close()
+		start := time.Now()
+		sw := &responseWriter{maxBodySize: s.cfg.MaxBodySize}
+		s.candidate.ServeHTTP(sw, shadowReq)
+		duration := time.Since(start)
+
+		s.report(s.compare(shadowReq, <-primaryResult, sw, duration))
+	}()
+
+	tr := &teeRecorder{ResponseWriter: w, status: http.StatusOK, maxBodySize: s.cfg.MaxBodySize}
+	defer func() {
+		primaryResult <- tr
+	}()
+	req := r.Clone(r.Context())
+	req.Body = primaryBody
+	s.primary.ServeHTTP(tr, req)
+
+	// the request body cannot be read once this handler returns, so read the remainder into the split buffer for
+	// the shadow request before returning
+	//nolint:errcheck // any error is returned to the shadow request
+	io.Copy(io.Discard, primaryBody)
+	primaryBody.Close()
+}
+
+// sampled returns true if the request is to be replayed to the candidate
+func (s *shadowHandler) sampled(r *http.Request) bool {
+	if !slices.Contains(s.cfg.Methods, r.Method) || s.cfg.SampleRate <= 0 {
+		return false
+	}
+	return s.cfg.SampleRate >= 1 || rand.Float64() < s.cfg.SampleRate
+}
+
+func (s *shadowHandler) compare(req *http.Request, primary *teeRecorder, shadow *responseWriter, duration time.Duration) ShadowResult {
+	result := ShadowResult{
+		Request:       req,
+		RequestID:     request.GetRequestId(req.Context()),
+		PrimaryStatus: primary.status,
+		ShadowStatus:  shadow.StatusCode(),
+		Duration:      duration,
+		Truncated:     primary.truncated || shadow.truncated,
+	}
+	if msg := shadow.Header().Get(upstreamErrorHeader); msg != "" {
+		result.Err = errors.New(msg)
+		return result
+	}
+	if !result.Truncated {
+		result.Diffs = DiffJSON(primary.body.Bytes(), shadow.Body(), s.cfg.IgnoreFields)
+	}
+	return result
+}
+
+func (s *shadowHandler) report(result ShadowResult) {
+	if s.cfg.OnResult != nil {
+		s.cfg.OnResult(result)
+		return
+	}
+
+	ctx := result.Request.Context()
+	logData := log.Data{
+		"method":         result.Request.Method,
+		"path":           result.Request.URL.Path,
+		"request_id":     result.RequestID,
+		"primary_status": result.PrimaryStatus,
+		"shadow_status":  result.ShadowStatus,
+		"duration_ms":    result.Duration.Milliseconds(),
+	}
+	switch {
+	case result.Err != nil:
+		log.Error(ctx, "shadow request failed", result.Err, logData)
+	case result.Matched():
+		log.Info(ctx, "shadow response matched", logData)
+	default:
+		logData["diffs"] = result.Diffs
+		logData["truncated"] = result.Truncated
+		log.Warn(ctx, "shadow response differs", logData)
+	}
+}
+
+// teeRecorder records the status and up to maxBodySize bytes of the body written to a http.ResponseWriter
+type teeRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	maxBodySize int
+	truncated   bool
+}
+
+func (tr *teeRecorder) WriteHeader(status int) {
+	if !tr.wroteHeader {
+		tr.status = status
+		tr.wroteHeader = true
+	}
+	tr.ResponseWriter.WriteHeader(status)
+}
+
+func (tr *teeRecorder) Write(b []byte) (int, error) {
+	tr.wroteHeader = true
+	if tr.body.Len()+len(b) > tr.maxBodySize {
+		tr.truncated = true
+	} else {
+		tr.body.Write(b)
+	}
+	return tr.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, so that streamed responses are flushed to the client
+func (tr *teeRecorder) Flush() {
+	if f, ok := tr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController
+func (tr *teeRecorder) Unwrap() http.ResponseWriter {
+	return tr.ResponseWriter
+}
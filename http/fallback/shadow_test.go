@@ -0,0 +1,158 @@
+package fallback
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func jsonHandler(status int, body string, requests chan<- string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if requests != nil {
+			requests <- string(b)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func TestShadow(t *testing.T) {
+	Convey("Given a shadow handler comparing a primary and candidate handler", t, func() {
+		candidateRequests := make(chan string, 1)
+		results := make(chan ShadowResult, 1)
+		primary := jsonHandler(http.StatusOK, `{"id":"cpih","title":"CPIH","last_updated":"yesterday"}`, nil)
+		candidate := jsonHandler(http.StatusOK, `{"id":"cpih","title":"CPIH index","last_updated":"today"}`, candidateRequests)
+		handler := Shadow(primary, candidate, ShadowConfig{
+			SampleRate:   1,
+			Methods:      []string{http.MethodPost},
+			IgnoreFields: []string{"last_updated"},
+			OnResult:     func(result ShadowResult) { results <- result },
+		})
+
+		Convey("When a request is made", func() {
+			r := httptest.NewRequest(http.MethodPost, "/datasets", strings.NewReader(`{"id":"cpih"}`))
+			r = r.WithContext(request.WithRequestId(r.Context(), "request-123"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			Convey("Then the primary response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, `{"id":"cpih","title":"CPIH","last_updated":"yesterday"}`)
+			})
+
+			Convey("Then the request is replayed to the candidate and the differences reported", func() {
+				So(<-candidateRequests, ShouldEqual, `{"id":"cpih"}`)
+				result := <-results
+				So(result.RequestID, ShouldEqual, "request-123")
+				So(result.PrimaryStatus, ShouldEqual, http.StatusOK)
+				So(result.ShadowStatus, ShouldEqual, http.StatusOK)
+				So(result.Diffs, ShouldResemble, []Diff{{Path: "title", Kind: DiffChanged, Primary: "CPIH", Shadow: "CPIH index"}})
+				So(result.Matched(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a shadow handler whose candidate returns the same response", t, func() {
+		results := make(chan ShadowResult, 1)
+		handler := Shadow(jsonHandler(http.StatusOK, `{"a":1}`, nil), jsonHandler(http.StatusOK, `{"a": 1}`, nil), ShadowConfig{
+			SampleRate: 1,
+			OnResult:   func(result ShadowResult) { results <- result },
+		})
+
+		Convey("When a request is made", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			Convey("Then the responses match", func() {
+				So((<-results).Matched(), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a shadow handler with responses larger than the maximum body size", t, func() {
+		results := make(chan ShadowResult, 1)
+		handler := Shadow(jsonHandler(http.StatusOK, `{"a":1}`, nil), jsonHandler(http.StatusOK, `{"a":2}`, nil), ShadowConfig{
+			SampleRate:  1,
+			MaxBodySize: 4,
+			OnResult:    func(result ShadowResult) { results <- result },
+		})
+
+		Convey("When a request is made", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			Convey("Then the full primary response is returned but not compared", func() {
+				So(w.Body.String(), ShouldEqual, `{"a":1}`)
+				result := <-results
+				So(result.Truncated, ShouldBeTrue)
+				So(result.Diffs, ShouldBeEmpty)
+				So(result.Matched(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a shadow handler with a sample rate", t, func() {
+		results := make(chan ShadowResult, 100)
+		handler := Shadow(jsonHandler(http.StatusOK, `{}`, nil), jsonHandler(http.StatusOK, `{}`, nil), ShadowConfig{
+			SampleRate: 0.000001,
+			OnResult:   func(result ShadowResult) { results <- result },
+		})
+
+		Convey("When requests are made", func() {
+			for i := 0; i < 100; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			}
+
+			Convey("Then only a sample are replayed", func() {
+				So(len(results), ShouldBeLessThan, 100)
+			})
+		})
+	})
+
+	Convey("Given a shadow handler with the zero value config", t, func() {
+		candidateRequests := make(chan string, 1)
+		handler := Shadow(jsonHandler(http.StatusOK, `{}`, nil), jsonHandler(http.StatusOK, `{}`, candidateRequests), ShadowConfig{})
+
+		Convey("When a request is made", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			Convey("Then the primary response is returned and the request is not replayed", func() {
+				So(w.Body.String(), ShouldEqual, `{}`)
+				So(candidateRequests, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a shadow handler replaying every request with the default methods", t, func() {
+		results := make(chan ShadowResult, 2)
+		handler := Shadow(jsonHandler(http.StatusOK, `{}`, nil), jsonHandler(http.StatusOK, `{}`, nil), ShadowConfig{
+			SampleRate: 1,
+			OnResult:   func(result ShadowResult) { results <- result },
+		})
+
+		Convey("When a write request is made", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/datasets", strings.NewReader(`{}`)))
+
+			Convey("Then it is only served by the primary", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(results, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a read request is made", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then it is replayed to the candidate", func() {
+				So((<-results).Request.Method, ShouldEqual, http.MethodGet)
+			})
+		})
+	})
+}
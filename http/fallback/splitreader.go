@@ -3,13 +3,40 @@ package fallback
 import (
 	"errors"
 	"io"
+	"sync"
 )
 
-// TODO implement mutexes
+const writeToChunkSize = 32 * 1024
+
+var (
+	// ErrSplitClosed is returned when reading from, or closing, a split that has already been closed
+	ErrSplitClosed = errors.New("reader already closed")
+	// ErrSplitBufferFull is returned when a split is too far ahead of the slowest split to read any more without
+	// exceeding the maximum buffer size, and the splitter is not configured to block
+	ErrSplitBufferFull = errors.New("split reader buffer full")
+)
+
+// SplitConfig is the configuration of a ReadCloserSplit
+type SplitConfig struct {
+	// MaxBuffer is the maximum number of bytes buffered between the slowest and fastest split. Zero is unbounded.
+	MaxBuffer int64
+	// BlockWhenFull makes a read block until the slowest split catches up, instead of returning ErrSplitBufferFull,
+	// when the buffer is full. Splits must then be read from different goroutines, or closed, to avoid a deadlock.
+	BlockWhenFull bool
+}
 
 type readCloserSplitter struct {
-	ReadCloser   io.ReadCloser
+	ReadCloser io.ReadCloser
+	cfg        SplitConfig
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// buf holds the bytes read from upstream that have not yet been read by every split, starting at offset base
+	buf          []byte
+	base         int64
 	maxBytesRead int64
+	reading      bool
+	err          error
 	splits       map[int]*splitReadCloser
 }
 
@@ -18,12 +45,22 @@ type readCloserSplitter struct {
 // then buffered until the remaining readers have been able to read them. This ensures that each reader is able to read
 // the entire upstream content but minimises the active memory usage which would otherwise be incurred of we slurped the
 // entire content upfront
+//
+// The returned readers are safe to read from different goroutines. The buffer is unbounded, see
+// ReadCloserSplitWithConfig to limit it.
 func ReadCloserSplit(readCloser io.ReadCloser, splits int) []io.ReadCloser {
+	return ReadCloserSplitWithConfig(readCloser, splits, SplitConfig{})
+}
+
+// ReadCloserSplitWithConfig is ReadCloserSplit with a configurable maximum buffer size. The returned readers also
+// implement [io.WriterTo].
+func ReadCloserSplitWithConfig(readCloser io.ReadCloser, splits int, cfg SplitConfig) []io.ReadCloser {
 	s := &readCloserSplitter{
-		ReadCloser:   readCloser,
-		maxBytesRead: 0,
-		splits:       make(map[int]*splitReadCloser),
+		ReadCloser: readCloser,
+		cfg:        cfg,
+		splits:     make(map[int]*splitReadCloser),
 	}
+	s.cond = sync.NewCond(&s.mu)
 
 	closers := make([]io.ReadCloser, splits)
 	for i := 0; i < splits; i++ {
@@ -37,92 +74,185 @@ func ReadCloserSplit(readCloser io.ReadCloser, splits int) []io.ReadCloser {
 	return closers
 }
 
-func (s *readCloserSplitter) upstreamRead(toLength int64) {
-	if toLength <= s.maxBytesRead {
-		return
+// fill reads from upstream, at most once, so that bytes are buffered up to toLength for the split, unless they are
+// already buffered. It returns the error that applies if the split has no buffered bytes to read. The lock must be
+// held when calling fill.
+func (s *readCloserSplitter) fill(split *splitReadCloser, toLength int64) error {
+	for {
+		if split.closed {
+			return ErrSplitClosed
+		}
+		if s.err != nil {
+			return s.err
+		}
+		if toLength <= s.maxBytesRead {
+			return nil
+		}
+		if s.reading {
+			// another split is reading from upstream
+			s.cond.Wait()
+			continue
+		}
+
+		toRead := toLength - s.maxBytesRead
+		if s.cfg.MaxBuffer > 0 {
+			toRead = min(toRead, s.cfg.MaxBuffer-(s.maxBytesRead-s.minOffset()))
+		}
+		if toRead > 0 {
+			s.upstreamRead(toRead)
+			return s.err
+		}
+
+		if split.bytesRead < s.maxBytesRead {
+			// the buffer is full, but there are buffered bytes for this split to read
+			return nil
+		}
+		if !s.cfg.BlockWhenFull {
+			return ErrSplitBufferFull
+		}
+		s.cond.Wait()
 	}
-	toRead := toLength - s.maxBytesRead
+}
+
+// upstreamRead reads up to toRead bytes from upstream, releasing the lock while reading so that other splits can read
+// buffered bytes
+func (s *readCloserSplitter) upstreamRead(toRead int64) {
+	s.reading = true
+	s.mu.Unlock()
 	buf := make([]byte, toRead)
 	n, err := s.ReadCloser.Read(buf)
+	s.mu.Lock()
+	s.reading = false
+
 	if n > 0 {
+		s.buf = append(s.buf, buf[:n]...)
 		s.maxBytesRead += int64(n)
-		for _, split := range s.splits {
-			split.addUnreadBytes(buf[:n])
-		}
 	}
 	if err != nil {
-		for _, split := range s.splits {
-			split.setUpstreamError(err)
-		}
+		s.err = err
 	}
+	s.cond.Broadcast()
+}
+
+// minOffset returns the offset of the slowest open split
+func (s *readCloserSplitter) minOffset() int64 {
+	offset := s.maxBytesRead
+	for _, split := range s.splits {
+		offset = min(offset, split.bytesRead)
+	}
+	return offset
+}
+
+// release discards buffered bytes that have been read by every open split
+func (s *readCloserSplitter) release() {
+	drop := s.minOffset() - s.base
+	if drop <= 0 {
+		return
+	}
+	if drop == int64(len(s.buf)) {
+		s.buf = nil
+	} else {
+		s.buf = s.buf[drop:]
+	}
+	s.base += drop
+	s.cond.Broadcast()
+}
+
+// buffered returns the bytes buffered for the split to read. The lock must be held when calling buffered.
+func (s *readCloserSplitter) buffered(split *splitReadCloser) []byte {
+	return s.buf[split.bytesRead-s.base:]
 }
 
 func (s *readCloserSplitter) CloseSplit(id int) error {
-	if _, ok := s.splits[id]; !ok {
-		return errors.New("reader already closed")
+	s.mu.Lock()
+	split, ok := s.splits[id]
+	if !ok {
+		s.mu.Unlock()
+		return ErrSplitClosed
 	}
+	split.closed = true
 	delete(s.splits, id)
+	s.release()
+	s.cond.Broadcast()
+	last := len(s.splits) == 0
+	s.mu.Unlock()
+
 	// If this is the last split to be closed then close the upstream reader too
-	if len(s.splits) == 0 {
+	if last {
 		return s.ReadCloser.Close()
 	}
 	return nil
 }
 
 type splitReadCloser struct {
-	Id            int
-	splitter      *readCloserSplitter
-	bytesRead     int64
-	unreadBytes   []*[]byte
-	upstreamError error
+	Id        int
+	splitter  *readCloserSplitter
+	bytesRead int64
+	closed    bool
 }
 
-var _ io.ReadCloser = &splitReadCloser{}
+var (
+	_ io.ReadCloser = &splitReadCloser{}
+	_ io.WriterTo   = &splitReadCloser{}
+)
 
 func (s *splitReadCloser) Read(p []byte) (n int, err error) {
-	toLength := s.bytesRead + int64(len(p))
-	s.splitter.upstreamRead(toLength)
+	s.splitter.mu.Lock()
+	defer s.splitter.mu.Unlock()
 
-	n = s.getUnreadBytes(p)
-	s.bytesRead += int64(n)
+	err = s.splitter.fill(s, s.bytesRead+int64(len(p)))
+	if s.closed {
+		return 0, err
+	}
 
-	if n == 0 && len(s.unreadBytes) == 0 && s.upstreamError != nil {
-		return n, s.upstreamError
+	n = copy(p, s.splitter.buffered(s))
+	if n > 0 {
+		s.bytesRead += int64(n)
+		s.splitter.release()
+		return n, nil
 	}
-	return n, nil
+	return 0, err
 }
 
-func (s *splitReadCloser) addUnreadBytes(b []byte) {
-	s.unreadBytes = append(s.unreadBytes, &b)
-}
+// WriteTo writes the split's remaining bytes to w directly from the shared buffer, until upstream returns io.EOF
+func (s *splitReadCloser) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for {
+		s.splitter.mu.Lock()
+		err := s.splitter.fill(s, s.bytesRead+writeToChunkSize)
+		var b []byte
+		if !s.closed {
+			b = s.splitter.buffered(s)
+		}
+		s.splitter.mu.Unlock()
 
-func (s *splitReadCloser) setUpstreamError(err error) {
-	s.upstreamError = err
-}
+		if len(b) == 0 {
+			if err == io.EOF {
+				return written, nil
+			}
+			if err != nil {
+				return written, err
+			}
+			continue
+		}
 
-func (s *splitReadCloser) getUnreadBytes(p []byte) int {
-	if len(s.unreadBytes) == 0 {
-		return 0
-	}
-	read := 0
-	remaining := len(p)
-	for remaining > 0 && len(s.unreadBytes) > 0 {
-		firstSlice := s.unreadBytes[0]
-		lenFirstSlice := len(*firstSlice)
-		if remaining < lenFirstSlice {
-			// take 'remaining' x bytes from s[0] and leave rest in slice
-			read += copy(p[read:read+remaining], (*firstSlice)[:remaining])
-			newFirstSlice := (*firstSlice)[remaining:]
-			s.unreadBytes[0] = &newFirstSlice
-			remaining = 0
-		} else { // remaining >= len s[0]
-			// take all slice and delete s[0]
-			read += copy(p[read:read+lenFirstSlice], *firstSlice)
-			s.unreadBytes = s.unreadBytes[1:] // TODO maybe reslice to save on wasted mem?
-			remaining -= lenFirstSlice
+		// bytes in the shared buffer are never modified, and are not released until this split has read them, so
+		// they can be written without holding the lock
+		n, werr := w.Write(b)
+		written += int64(n)
+
+		s.splitter.mu.Lock()
+		s.bytesRead += int64(n)
+		s.splitter.release()
+		s.splitter.mu.Unlock()
+
+		if werr != nil {
+			return written, werr
+		}
+		if n < len(b) {
+			return written, io.ErrShortWrite
 		}
 	}
-	return read
 }
 
 func (s *splitReadCloser) Close() error {
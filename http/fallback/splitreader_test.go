@@ -3,7 +3,9 @@ package fallback
 import (
 	"bytes"
 	"io"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -306,6 +308,160 @@ func TestReadCloserSplit_Errors(t *testing.T) {
 	})
 }
 
+func TestReadCloserSplit_Concurrent(t *testing.T) {
+	Convey("Given a large upstream ReadCloser split into readers on different goroutines", t, func() {
+		data := bytes.Repeat([]byte("0123456789"), 100000)
+		splitRCs := ReadCloserSplitWithConfig(mockReadCloser(data), 4, SplitConfig{MaxBuffer: 4096, BlockWhenFull: true})
+
+		Convey("When every reader reads to the end concurrently", func() {
+			results := make([][]byte, len(splitRCs))
+			errs := make([]error, len(splitRCs))
+			var wg sync.WaitGroup
+			for i, rc := range splitRCs {
+				wg.Add(1)
+				go func(i int, rc io.ReadCloser) {
+					defer wg.Done()
+					defer rc.Close()
+					buf := make([]byte, 100+i*333)
+					for {
+						n, err := rc.Read(buf)
+						results[i] = append(results[i], buf[:n]...)
+						if err != nil {
+							if err != io.EOF {
+								errs[i] = err
+							}
+							return
+						}
+					}
+				}(i, rc)
+			}
+			wg.Wait()
+
+			Convey("Then each reader reads the entire content without error", func() {
+				for i := range splitRCs {
+					So(errs[i], ShouldBeNil)
+					So(results[i], ShouldResemble, data)
+				}
+			})
+		})
+	})
+}
+
+func TestReadCloserSplit_Bounded(t *testing.T) {
+	Convey("Given an upstream ReadCloser split into 2 readers with a maximum buffer of 4 bytes", t, func() {
+		upstreamRC := mockReadCloser([]byte("some.data.to.test"))
+		splitRCs := ReadCloserSplitWithConfig(upstreamRC, 2, SplitConfig{MaxBuffer: 4})
+		rc1 := splitRCs[0]
+		rc2 := splitRCs[1]
+
+		Convey("When the first reader reads beyond the maximum buffer", func() {
+			read1 := make([]byte, 10)
+			n, err := rc1.Read(read1)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 4)
+			So(read1[:n], ShouldResemble, []byte("some"))
+
+			Convey("Then further reads fail until the second reader catches up", func() {
+				_, err = rc1.Read(read1)
+				So(err, ShouldEqual, ErrSplitBufferFull)
+
+				read2 := make([]byte, 2)
+				n, err = rc2.Read(read2)
+				So(err, ShouldBeNil)
+				So(read2[:n], ShouldResemble, []byte("so"))
+
+				n, err = rc1.Read(read1)
+				So(err, ShouldBeNil)
+				So(read1[:n], ShouldResemble, []byte(".d"))
+			})
+
+			Convey("Then bytes read by both readers are released", func() {
+				read2 := make([]byte, 3)
+				_, err = rc2.Read(read2)
+				So(err, ShouldBeNil)
+				splitter := rc1.(*splitReadCloser).splitter
+				So(splitter.buf, ShouldResemble, []byte("e"))
+				So(splitter.base, ShouldEqual, 3)
+			})
+
+			Convey("Then closing the second reader allows the first to continue", func() {
+				So(rc2.Close(), ShouldBeNil)
+				n, err = rc1.Read(read1)
+				So(err, ShouldBeNil)
+				So(read1[:n], ShouldResemble, []byte(".dat"))
+				So(rc1.(*splitReadCloser).splitter.buf, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a bounded split configured to block when full", t, func() {
+		splitRCs := ReadCloserSplitWithConfig(mockReadCloser([]byte("some.data.to.test")), 2, SplitConfig{MaxBuffer: 4, BlockWhenFull: true})
+		rc1 := splitRCs[0]
+		rc2 := splitRCs[1]
+		_, err := rc1.Read(make([]byte, 4))
+		So(err, ShouldBeNil)
+
+		Convey("When the first reader reads while the buffer is full", func() {
+			done := make(chan []byte)
+			go func() {
+				read1 := make([]byte, 4)
+				n, _ := rc1.Read(read1)
+				done <- read1[:n]
+			}()
+
+			Convey("Then it blocks until the second reader catches up", func() {
+				select {
+				case <-done:
+					t.Fatal("read did not block")
+				case <-time.After(20 * time.Millisecond):
+				}
+
+				_, err := rc2.Read(make([]byte, 4))
+				So(err, ShouldBeNil)
+				So(<-done, ShouldResemble, []byte(".dat"))
+			})
+		})
+	})
+}
+
+func TestReadCloserSplit_WriteTo(t *testing.T) {
+	Convey("Given an upstream ReadCloser split into 2 readers", t, func() {
+		data := bytes.Repeat([]byte("some.data.to.test"), 10000)
+		splitRCs := ReadCloserSplit(mockReadCloser(data), 2)
+
+		Convey("When each reader is copied to a writer", func() {
+			var w1, w2 bytes.Buffer
+			n1, err1 := io.Copy(&w1, splitRCs[0])
+			n2, err2 := io.Copy(&w2, splitRCs[1])
+
+			Convey("Then the entire content is written using WriteTo", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+				So(n1, ShouldEqual, len(data))
+				So(n2, ShouldEqual, len(data))
+				So(w1.Bytes(), ShouldResemble, data)
+				So(w2.Bytes(), ShouldResemble, data)
+			})
+
+			Convey("Then the buffer is released", func() {
+				So(splitRCs[0].(*splitReadCloser).splitter.buf, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a reader is closed", func() {
+			So(splitRCs[0].Close(), ShouldBeNil)
+
+			Convey("Then reading or closing it again returns an error", func() {
+				_, err := splitRCs[0].Read(make([]byte, 1))
+				So(err, ShouldEqual, ErrSplitClosed)
+				_, err = io.Copy(io.Discard, splitRCs[0])
+				So(err, ShouldEqual, ErrSplitClosed)
+				So(splitRCs[0].Close(), ShouldEqual, ErrSplitClosed)
+			})
+		})
+	})
+}
+
 // Mock for io.ReadCloser
 type readCloserMock struct {
 	data       []byte
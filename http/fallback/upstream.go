@@ -0,0 +1,93 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/ONSdigital/dp-net/v3/handlers/reverseproxy"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// upstreamErrorHeader marks the response written when an upstream request fails, so that it can trigger a fallback.
+// It is never returned to the caller, as the marked response is always discarded.
+const upstreamErrorHeader = "X-Fallback-Upstream-Error"
+
+// Upstream is a remote service that requests are proxied to
+type Upstream struct {
+	URL *url.URL
+	// Timeout bounds the whole request to the upstream, including reading the response body. Zero is no timeout.
+	Timeout time.Duration
+	// Director and ModifyResponse are passed to reverseproxy.Create
+	Director       func(*http.Request)
+	ModifyResponse func(*http.Response) error
+}
+
+// UpstreamConfig is the configuration of a handler that proxies requests to a primary upstream, and falls back to
+// a secondary upstream
+type UpstreamConfig struct {
+	Primary   Upstream
+	Secondary Upstream
+	// Conditions on the primary response that trigger the fallback, in addition to connection errors and timeouts
+	Conditions []Condition
+	// Shadow sends requests to the secondary upstream as well, for comparison, but always returns the primary
+	// response. The secondary request is made asynchronously, see Shadow.
+	Shadow bool
+	// ShadowConfig configures shadow mode. A zero SampleRate replays every request, as setting Shadow opts in to
+	// replaying requests; set a SampleRate between 0 and 1 to replay a proportion of them.
+	ShadowConfig ShadowConfig
+	// AttemptsHeader records each upstream tried in the named response header, see Alternative
+	AttemptsHeader string
+}
+
+// Upstreams returns a handler that proxies requests to the primary upstream, falling back to the secondary upstream
+// when the primary response matches any of the configured conditions, or when the primary request fails to connect
+// or times out. In shadow mode the primary response is always returned, see UpstreamConfig.Shadow.
+func Upstreams(cfg UpstreamConfig) http.Handler {
+	if cfg.Shadow {
+		if cfg.ShadowConfig.SampleRate == 0 {
+			cfg.ShadowConfig.SampleRate = 1
+		}
+		return Shadow(newUpstreamProxy(cfg.Primary, false), newUpstreamProxy(cfg.Secondary, true), cfg.ShadowConfig)
+	}
+
+	return &Alternative{
+		TryHandler: newUpstreamProxy(cfg.Primary, true),
+		Fallbacks: []Fallback{{
+			Conditions: append([]Condition{upstreamFailed}, cfg.Conditions...),
+			Handler:    newUpstreamProxy(cfg.Secondary, false),
+		}},
+		AttemptsHeader: cfg.AttemptsHeader,
+	}
+}
+
+// upstreamFailed is a Condition that matches responses marked by newUpstreamProxy as failed
+func upstreamFailed(_ int, header http.Header) bool {
+	return header.Get(upstreamErrorHeader) != ""
+}
+
+// newUpstreamProxy creates a reverse proxy to the upstream. If markErrors is set, the response written when the
+// upstream request fails is marked with the upstreamErrorHeader.
+func newUpstreamProxy(u Upstream, markErrors bool) http.Handler {
+	proxy := reverseproxy.Create(u.URL, u.Director, u.ModifyResponse).(*httputil.ReverseProxy)
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		log.Error(req.Context(), "upstream request failed", err, log.Data{"upstream": u.URL.String()})
+		// a cancelled request means the caller has gone away, so there is no point falling back
+		if markErrors && !errors.Is(err, context.Canceled) {
+			w.Header().Set(upstreamErrorHeader, err.Error())
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	if u.Timeout <= 0 {
+		return proxy
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), u.Timeout)
+		defer cancel()
+		proxy.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
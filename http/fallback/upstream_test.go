@@ -0,0 +1,212 @@
+package fallback
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newUpstream starts a test server which records the request body and responds with the status and designation
+func newUpstream(status int, designation string, delay time.Duration) (*httptest.Server, chan string) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+		time.Sleep(delay)
+		w.Header().Set(testHeader, designation)
+		w.WriteHeader(status)
+		w.Write([]byte(designation + " response"))
+	}))
+	return server, bodies
+}
+
+func upstream(server *httptest.Server, timeout time.Duration) Upstream {
+	u, _ := url.Parse(server.URL)
+	return Upstream{URL: u, Timeout: timeout}
+}
+
+func TestUpstreams(t *testing.T) {
+	Convey("Given a primary and secondary upstream", t, func() {
+		secondary, secondaryBodies := newUpstream(http.StatusOK, secondaryDesignation, 0)
+		defer secondary.Close()
+
+		Convey("When the primary returns a fallback status", func() {
+			primary, primaryBodies := newUpstream(http.StatusNotFound, primaryDesignation, 0)
+			defer primary.Close()
+			handler := Upstreams(UpstreamConfig{
+				Primary:        upstream(primary, 0),
+				Secondary:      upstream(secondary, 0),
+				Conditions:     []Condition{StatusIs(http.StatusNotFound)},
+				AttemptsHeader: DefaultAttemptsHeader,
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/datasets", strings.NewReader("request body")))
+
+			Convey("Then the request, including its body, is sent to both upstreams", func() {
+				So(<-primaryBodies, ShouldEqual, "request body")
+				So(<-secondaryBodies, ShouldEqual, "request body")
+			})
+
+			Convey("Then the secondary response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, secondaryDesignation+" response")
+				So(w.Header().Values(DefaultAttemptsHeader), ShouldResemble, []string{"0=404", "1=200"})
+			})
+		})
+
+		Convey("When the primary returns a non-fallback status", func() {
+			primary, _ := newUpstream(http.StatusOK, primaryDesignation, 0)
+			defer primary.Close()
+			handler := Upstreams(UpstreamConfig{
+				Primary:    upstream(primary, 0),
+				Secondary:  upstream(secondary, 0),
+				Conditions: []Condition{StatusIs(http.StatusNotFound)},
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then the primary response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, primaryDesignation+" response")
+				So(secondaryBodies, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the primary cannot be connected to", func() {
+			primary, _ := newUpstream(http.StatusOK, primaryDesignation, 0)
+			primary.Close()
+			handler := Upstreams(UpstreamConfig{
+				Primary:   upstream(primary, 0),
+				Secondary: upstream(secondary, 0),
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then the secondary response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, secondaryDesignation+" response")
+				So(w.Header().Get(upstreamErrorHeader), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the primary exceeds its timeout", func() {
+			primary, _ := newUpstream(http.StatusOK, primaryDesignation, 200*time.Millisecond)
+			defer primary.Close()
+			handler := Upstreams(UpstreamConfig{
+				Primary:   upstream(primary, 20*time.Millisecond),
+				Secondary: upstream(secondary, time.Second),
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then the secondary response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, secondaryDesignation+" response")
+			})
+		})
+
+		Convey("When the secondary cannot be connected to either", func() {
+			primary, _ := newUpstream(http.StatusOK, primaryDesignation, 0)
+			primary.Close()
+			secondary.Close()
+			handler := Upstreams(UpstreamConfig{
+				Primary:   upstream(primary, 0),
+				Secondary: upstream(secondary, 0),
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then a bad gateway response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadGateway)
+				So(w.Header().Get(upstreamErrorHeader), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestUpstreamsShadow(t *testing.T) {
+	Convey("Given a primary and secondary upstream in shadow mode", t, func() {
+		primary, _ := newUpstream(http.StatusOK, primaryDesignation, 0)
+		defer primary.Close()
+		secondary, secondaryBodies := newUpstream(http.StatusNotFound, secondaryDesignation, 50*time.Millisecond)
+		defer secondary.Close()
+
+		results := make(chan ShadowResult, 1)
+		handler := Upstreams(UpstreamConfig{
+			Primary:   upstream(primary, 0),
+			Secondary: upstream(secondary, time.Second),
+			Shadow:    true,
+			ShadowConfig: ShadowConfig{
+				SampleRate: 1,
+				Methods:    []string{http.MethodGet, http.MethodPost},
+				OnResult:   func(result ShadowResult) { results <- result },
+			},
+		})
+
+		Convey("When a request is made", func() {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			resp, err := http.Post(server.URL+"/datasets", "text/plain", strings.NewReader("request body"))
+			So(err, ShouldBeNil)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the primary response is returned", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(string(body), ShouldEqual, primaryDesignation+" response")
+			})
+
+			Convey("Then the request is replayed to the secondary and the result reported", func() {
+				So(<-secondaryBodies, ShouldEqual, "request body")
+				result := <-results
+				So(result.Err, ShouldBeNil)
+				So(result.PrimaryStatus, ShouldEqual, http.StatusOK)
+				So(result.ShadowStatus, ShouldEqual, http.StatusNotFound)
+				So(result.Request.URL.Path, ShouldEqual, "/datasets")
+			})
+		})
+
+		Convey("When the secondary cannot be connected to", func() {
+			secondary.Close()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then the primary response is returned and the error reported", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				result := <-results
+				So(result.Err, ShouldNotBeNil)
+				So(result.ShadowStatus, ShouldEqual, http.StatusBadGateway)
+			})
+		})
+	})
+
+	Convey("Given a primary and secondary upstream in shadow mode with a zero value ShadowConfig", t, func() {
+		primary, _ := newUpstream(http.StatusOK, primaryDesignation, 0)
+		defer primary.Close()
+		secondary, secondaryBodies := newUpstream(http.StatusOK, secondaryDesignation, 0)
+		defer secondary.Close()
+
+		handler := Upstreams(UpstreamConfig{
+			Primary:   upstream(primary, 0),
+			Secondary: upstream(secondary, time.Second),
+			Shadow:    true,
+		})
+
+		Convey("When a GET request is made", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody))
+
+			Convey("Then the primary response is returned and the request is replayed to the secondary", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, primaryDesignation+" response")
+				So(<-secondaryBodies, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Listener network types
+const (
+	NetworkTCP  = "tcp"
+	NetworkUnix = "unix"
+)
+
+// listener is an additional address the server listens on, with its own http.Server so that
+// it can serve a different handler
+type listener struct {
+	network string
+	addr    string
+	ln      net.Listener
+	handler http.Handler
+	server  *http.Server
+}
+
+// ListenerInfo describes an additional listener registered with the server
+type ListenerInfo struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+}
+
+// AddAddress adds a TCP address for the server to listen on, in addition to Addr. If handler is
+// nil the server's handler is used. The server's middleware and timeouts apply to all listeners.
+func (s *Server) AddAddress(addr string, handler http.Handler) {
+	s.listeners = append(s.listeners, &listener{network: NetworkTCP, addr: addr, handler: handler})
+}
+
+// AddUnixSocket adds a Unix domain socket for the server to listen on, in addition to Addr.
+// Any stale socket file at path is removed before listening. If handler is nil the server's
+// handler is used.
+func (s *Server) AddUnixSocket(path string, handler http.Handler) {
+	s.listeners = append(s.listeners, &listener{network: NetworkUnix, addr: path, handler: handler})
+}
+
+// AddListener adds a caller-supplied net.Listener for the server to serve on, in addition to
+// Addr, e.g. for socket activation. If handler is nil the server's handler is used.
+func (s *Server) AddListener(ln net.Listener, handler http.Handler) {
+	s.listeners = append(s.listeners, &listener{network: ln.Addr().Network(), addr: ln.Addr().String(), ln: ln, handler: handler})
+}
+
+// Listeners returns the additional listeners registered with the server
+func (s *Server) Listeners() []ListenerInfo {
+	infos := make([]ListenerInfo, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		infos = append(infos, ListenerInfo{Network: l.network, Address: l.addr})
+	}
+	return infos
+}
+
+// ServeListener serves on the provided net.Listener instead of listening on Addr, and on any
+// additional listeners. It otherwise behaves as ListenAndServe.
+func (s *Server) ServeListener(ln net.Listener) error {
+	s.mainListener = ln
+	return s.ListenAndServe()
+}
+
+// prepListeners creates a http.Server for each additional listener, wrapping its handler
+// with the provided middleware chain and the server's request timeout
+func (s *Server) prepListeners(wrap func(http.Handler) http.Handler, base http.Handler) {
+	for _, l := range s.listeners {
+		handler := l.handler
+		if handler == nil {
+			handler = base
+		}
+		handler = wrap(handler)
+		writeTimeout := s.WriteTimeout
+		if s.RequestTimeout > 0 {
+			if writeTimeout <= s.RequestTimeout {
+				writeTimeout = s.RequestTimeout + ResponseWriteGrace
+			}
+			handler = http.TimeoutHandler(handler, s.RequestTimeout, s.timeoutMessage())
+		}
+
+		l.server = &http.Server{
+			Handler:           handler,
+			Addr:              l.addr,
+			ReadTimeout:       s.ReadTimeout,
+			WriteTimeout:      writeTimeout,
+			ReadHeaderTimeout: s.ReadHeaderTimeout,
+			IdleTimeout:       s.IdleTimeout,
+			MaxHeaderBytes:    s.MaxHeaderBytes,
+			TLSConfig:         s.TLSConfig,
+			ErrorLog:          s.ErrorLog,
+			ConnState:         s.ConnState,
+			BaseContext:       s.BaseContext,
+			ConnContext:       s.ConnContext,
+		}
+	}
+}
+
+// serve listens on the listener's address, if not already listening, and serves requests.
+// TLS is used for TCP listeners if a certificate and key are provided.
+// The listener is wrapped by wrap before serving.
+func (l *listener) serve(certFile, keyFile string, wrap func(net.Listener) net.Listener) error {
+	ln := l.ln
+	if ln == nil {
+		var err error
+		if ln, err = listen(l.network, l.addr); err != nil {
+			return err
+		}
+	}
+
+	ln = wrap(ln)
+
+	if certFile != "" && keyFile != "" && l.network != NetworkUnix {
+		return l.server.ServeTLS(ln, certFile, keyFile)
+	}
+	return l.server.Serve(ln)
+}
+
+func listen(network, addr string) (net.Listener, error) {
+	if network == NetworkUnix {
+		if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(addr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return net.Listen(network, addr)
+}
+
+// serveListeners starts serving on all additional listeners, sending any error to errs
+func (s *Server) serveListeners(errs chan<- error) {
+	for _, l := range s.listeners {
+		go func(l *listener) {
+			err := l.serve(s.CertFile, s.KeyFile, s.wrapListener)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error(context.Background(), "http listener returned error", err, log.Data{"network": l.network, "address": l.addr})
+			}
+			errs <- err
+		}(l)
+	}
+}
+
+// shutdownListeners gracefully shuts down all additional listeners, returning the first error
+func (s *Server) shutdownListeners(ctx context.Context) error {
+	errs := make(chan error, len(s.listeners))
+	for _, l := range s.listeners {
+		go func(l *listener) {
+			if l.server == nil {
+				errs <- nil
+				return
+			}
+			errs <- l.server.Shutdown(ctx)
+		}(l)
+	}
+
+	var err error
+	for range s.listeners {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
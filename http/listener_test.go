@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func textHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func getBody(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, NetworkUnix, path)
+			},
+		},
+	}
+}
+
+func TestServerMultipleListeners(t *testing.T) {
+	doListenAndServe = func(httpServer *Server) error {
+		if httpServer.mainListener != nil {
+			return timeoutHandler(httpServer).Serve(httpServer.mainListener)
+		}
+		return timeoutHandler(httpServer).ListenAndServe()
+	}
+	doShutdown = func(ctx context.Context, httpServer *http.Server) error {
+		return httpServer.Shutdown(ctx)
+	}
+
+	Convey("Given a server with a main listener, an admin listener and a unix socket", t, func() {
+		mainLn, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		adminLn, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		socketPath := filepath.Join(t.TempDir(), "server.sock")
+
+		s := NewServer("", textHandler("public"))
+		s.HandleOSSignals = false
+		s.AddListener(adminLn, textHandler("admin"))
+		s.AddUnixSocket(socketPath, nil)
+		s.AddMiddleware("Header", func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("X-Middleware", "applied")
+				h.ServeHTTP(w, req)
+			})
+		})
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.ServeListener(mainLn)
+		}()
+
+		Convey("When requests are made to each listener", func() {
+			client := &http.Client{Timeout: time.Second}
+			publicBody, err := getBody(client, "http://"+mainLn.Addr().String())
+			So(err, ShouldBeNil)
+			adminResp, err := client.Get("http://" + adminLn.Addr().String())
+			So(err, ShouldBeNil)
+			adminBody, _ := io.ReadAll(adminResp.Body)
+			adminResp.Body.Close()
+
+			var socketBody string
+			for i := 0; i < 50; i++ {
+				if socketBody, err = getBody(unixClient(socketPath), "http://unix/"); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(err, ShouldBeNil)
+
+			Convey("Then each listener serves its own handler with the server's middleware", func() {
+				So(publicBody, ShouldEqual, "public")
+				So(string(adminBody), ShouldEqual, "admin")
+				So(adminResp.Header.Get("X-Middleware"), ShouldEqual, "applied")
+				So(socketBody, ShouldEqual, "public")
+			})
+
+			Convey("And Shutdown gracefully stops all listeners", func() {
+				So(s.Shutdown(context.Background()), ShouldBeNil)
+				So(<-errs, ShouldEqual, http.ErrServerClosed)
+
+				_, err := getBody(client, "http://"+adminLn.Addr().String())
+				So(err, ShouldNotBeNil)
+				_, err = os.Stat(socketPath)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+
+		Reset(func() {
+			_ = s.Shutdown(context.Background())
+		})
+	})
+
+	Convey("Given a server with an additional address that is already in use", t, func() {
+		inUse, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer inUse.Close()
+		mainLn, err := net.Listen(NetworkTCP, "127.0.0.1:0")
+		So(err, ShouldBeNil)
+
+		s := NewServer("", textHandler("public"))
+		s.HandleOSSignals = false
+		s.AddAddress(inUse.Addr().String(), textHandler("admin"))
+
+		Convey("When the server is started", func() {
+			err := s.ServeListener(mainLn)
+
+			Convey("Then the listen error is returned and the main listener is shut down", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldNotEqual, http.ErrServerClosed)
+
+				client := &http.Client{Timeout: time.Second}
+				_, err = getBody(client, "http://"+mainLn.Addr().String())
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the server is started with HandleOSSignals", func() {
+			s.HandleOSSignals = true
+			err := s.ServeListener(mainLn)
+
+			Convey("Then the listen error is returned and the main listener is shut down", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldNotEqual, http.ErrServerClosed)
+
+				client := &http.Client{Timeout: time.Second}
+				_, err = getBody(client, "http://"+mainLn.Addr().String())
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a stale socket file and a server listening on the same path", t, func() {
+		socketPath := filepath.Join(t.TempDir(), "stale.sock")
+		stale, err := net.Listen(NetworkUnix, socketPath)
+		So(err, ShouldBeNil)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		So(stale.Close(), ShouldBeNil)
+
+		Convey("When the socket is listened on", func() {
+			ln, err := listen(NetworkUnix, socketPath)
+
+			Convey("Then the stale socket is replaced", func() {
+				So(err, ShouldBeNil)
+				So(ln.Close(), ShouldBeNil)
+			})
+		})
+	})
+}
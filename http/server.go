@@ -1,6 +1,8 @@
 package http
 
 import (
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -34,6 +36,14 @@ type Server struct {
 	HandleOSSignals        bool
 	RequestTimeout         time.Duration
 	TimeoutMessage         string
+	// MaxConnections limits the number of simultaneous connections accepted by each listener.
+	// Once reached, new connections wait to be accepted until an open connection is closed.
+	MaxConnections int
+	// ListenerWrapper, if set, wraps every listener the server accepts connections from
+	ListenerWrapper func(net.Listener) net.Listener
+	mainListener    net.Listener
+	listeners       []*listener
+	conns           *connTracker
 }
 
 // NewServer creates a new server
@@ -58,6 +68,7 @@ func NewServer(bindAddr string, router http.Handler) *Server {
 		},
 		HandleOSSignals:        true,
 		DefaultShutdownTimeout: 10 * time.Second,
+		conns:                  newConnTracker(),
 	}
 }
 
@@ -70,6 +81,21 @@ func NewServerWithTimeout(bindAddr string, router http.Handler, timeout time.Dur
 	return server
 }
 
+// AddMiddleware registers a middleware constructor under the provided key.
+// If the key is already registered its constructor is replaced in place,
+// otherwise the middleware is appended to the end of the chain.
+func (s *Server) AddMiddleware(key string, mw alice.Constructor) {
+	if _, ok := s.middleware[key]; !ok {
+		s.middlewareOrder = append(s.middlewareOrder, key)
+	}
+	s.middleware[key] = mw
+}
+
+// Middleware returns the keys of the registered middleware, in the order they are applied
+func (s *Server) Middleware() []string {
+	return append([]string{}, s.middlewareOrder...)
+}
+
 func (s *Server) prep() {
 	var m []alice.Constructor
 	for _, v := range s.middlewareOrder {
@@ -80,7 +106,14 @@ func (s *Server) prep() {
 		panic("middleware not found: " + v)
 	}
 
-	s.Handler = alice.New(m...).Then(s.Handler)
+	if s.conns == nil {
+		s.conns = newConnTracker()
+	}
+	s.ConnState = s.conns.hook(s.ConnState)
+
+	chain := alice.New(m...)
+	s.prepListeners(chain.Then, s.Handler)
+	s.Handler = chain.Then(s.Handler)
 }
 
 // ListenAndServe sets up SIGINT/SIGTERM signals, builds the middleware
@@ -89,6 +122,12 @@ func (s *Server) prep() {
 // If CertFile/KeyFile are both set, the http.Server instance is started
 // using ListenAndServeTLS. Otherwise, ListenAndServe is used.
 //
+// Any additional listeners are started at the same time. If HandleOSSignals
+// is false, the first error returned by any listener is returned, after the
+// others have been shut down. If HandleOSSignals is true, an error from an
+// additional listener also shuts down the others and is returned, but an
+// error from the main server exits the process.
+//
 // Specifying one of CertFile/KeyFile without the other will panic.
 func (s *Server) ListenAndServe() error {
 	if s.HandleOSSignals {
@@ -118,11 +157,66 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		defer cancel()
 	}
 
-	return doShutdown(ctx, &s.Server)
+	if len(s.listeners) == 0 {
+		return doShutdown(ctx, &s.Server)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.shutdownListeners(ctx)
+	}()
+	err := doShutdown(ctx, &s.Server)
+	if lerr := <-errs; err == nil {
+		err = lerr
+	}
+	return err
 }
 
 func (s *Server) listenAndServe() error {
 	s.prep()
+	if len(s.listeners) == 0 {
+		return s.serveMain()
+	}
+
+	errs := make(chan error, len(s.listeners)+1)
+	s.serveListeners(errs)
+	go func() {
+		errs <- s.serveMain()
+	}()
+
+	err := <-errs
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		// shut down the servers that are still running, so that none are left serving without an owner, and wait for
+		// them all to stop
+		s.shutdownAfterError()
+		for range s.listeners {
+			<-errs
+		}
+	}
+	return err
+}
+
+// shutdownAfterError shuts down the servers that are still running after one of them has failed
+func (s *Server) shutdownAfterError() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.DefaultShutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		log.Error(ctx, "failed to shut down http server after listener error", err)
+	}
+}
+
+func (s *Server) serveMain() error {
+	if s.wrapsListeners() {
+		ln := s.mainListener
+		if ln == nil {
+			var err error
+			if ln, err = net.Listen(NetworkTCP, s.defaultAddr()); err != nil {
+				return err
+			}
+		}
+		s.mainListener = s.wrapListener(ln)
+	}
+
 	if s.CertFile != "" || s.KeyFile != "" {
 		return doListenAndServeTLS(s, s.CertFile, s.KeyFile)
 	}
@@ -134,31 +228,58 @@ func (s *Server) listenAndServeHandleOSSignals() error {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
-	s.listenAndServeAsync()
+	failed, mainStopped := s.listenAndServeAsync()
 
-	<-stop
+	select {
+	case <-stop:
+	case err := <-failed:
+		s.shutdownAfterError()
+		<-mainStopped
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	return s.Shutdown(ctx)
 }
 
-func (s *Server) listenAndServeAsync() {
+// listenAndServeAsync starts the server in the background, returning a channel that receives the error of the first
+// additional listener that fails, and a channel that is closed when the main server stops after being shut down
+func (s *Server) listenAndServeAsync() (failed <-chan error, mainStopped <-chan struct{}) {
 	s.prep()
-	if s.CertFile != "" || s.KeyFile != "" {
+	listenerFailed := make(chan error, 1)
+	stopped := make(chan struct{})
+	if len(s.listeners) > 0 {
+		errs := make(chan error, len(s.listeners))
+		s.serveListeners(errs)
 		go func() {
-			if err := doListenAndServeTLS(s, s.CertFile, s.KeyFile); err != nil {
-				log.Error(context.Background(), "http server returned error", err)
-				os.Exit(1)
-			}
-		}()
-	} else {
-		go func() {
-			if err := doListenAndServe(s); err != nil {
-				log.Error(context.Background(), "http server returned error", err)
-				os.Exit(1)
+			for range s.listeners {
+				if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Error(context.Background(), "http listener returned error", err)
+					listenerFailed <- err
+					return
+				}
 			}
 		}()
 	}
+	go func() {
+		defer close(stopped)
+		if err := s.serveMain(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(context.Background(), "http server returned error", err)
+			os.Exit(1)
+		}
+	}()
+	return listenerFailed, stopped
+}
+
+// defaultAddr returns Addr, or the default port for the server's scheme if Addr is empty
+func (s *Server) defaultAddr() string {
+	if s.Addr != "" {
+		return s.Addr
+	}
+	if s.CertFile != "" || s.KeyFile != "" {
+		return ":https"
+	}
+	return ":http"
 }
 
 func timeoutHandler(s *Server) *http.Server {
@@ -167,21 +288,30 @@ func timeoutHandler(s *Server) *http.Server {
 		if s.WriteTimeout <= s.RequestTimeout {
 			s.WriteTimeout = s.RequestTimeout + ResponseWriteGrace
 		}
-		timeoutMsg := "connection timeout"
-		if s.TimeoutMessage != "" {
-			timeoutMsg = s.TimeoutMessage
-		}
-		s.Handler = http.TimeoutHandler(s.Handler, s.RequestTimeout, timeoutMsg)
+		s.Handler = http.TimeoutHandler(s.Handler, s.RequestTimeout, s.timeoutMessage())
 	}
 
 	return &s.Server
 }
 
+func (s *Server) timeoutMessage() string {
+	if s.TimeoutMessage != "" {
+		return s.TimeoutMessage
+	}
+	return "connection timeout"
+}
+
 var doListenAndServe = func(httpServer *Server) error {
+	if httpServer.mainListener != nil {
+		return timeoutHandler(httpServer).Serve(httpServer.mainListener)
+	}
 	return timeoutHandler(httpServer).ListenAndServe()
 }
 
 var doListenAndServeTLS = func(httpServer *Server, certFile, keyFile string) error {
+	if httpServer.mainListener != nil {
+		return timeoutHandler(httpServer).ServeTLS(httpServer.mainListener, certFile, keyFile)
+	}
 	return timeoutHandler(httpServer).ListenAndServeTLS(certFile, keyFile)
 }
 
@@ -101,6 +101,24 @@ func TestNew(t *testing.T) {
 				}, ShouldPanicWith, "middleware not found: foo")
 			})
 
+			Convey("AddMiddleware should append new middleware to the chain", func() {
+				s := NewServer(":0", dummyHandler)
+
+				s.AddMiddleware("foo", func(h http.Handler) http.Handler { return h })
+				So(s.middleware, ShouldContainKey, "foo")
+				So(s.middlewareOrder, ShouldResemble, []string{RequestIDHandlerKey, LogHandlerKey, "foo"})
+
+				s.prep()
+				So(s.Handler, ShouldNotBeNil)
+			})
+
+			Convey("AddMiddleware should replace existing middleware in place", func() {
+				s := NewServer(":0", dummyHandler)
+
+				s.AddMiddleware(LogHandlerKey, func(h http.Handler) http.Handler { return h })
+				So(s.middlewareOrder, ShouldResemble, []string{RequestIDHandlerKey, LogHandlerKey})
+			})
+
 			Convey("ListenAndServe with invalid middleware should panic", func() {
 				s := NewServer(":0", dummyHandler)
 
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/ONSdigital/dp-net/v3/request"
 	"github.com/pkg/errors"
 )
 
@@ -12,10 +13,36 @@ type Builder struct {
 	URL *url.URL
 }
 
+// Forwarded header constants
+const (
+	ForwardedHostHeader       = "X-Forwarded-Host"
+	ForwardedPathPrefixHeader = "X-Forwarded-Path-Prefix"
+)
+
+// FromRequestOrDefault returns a Builder for the URL identified by the X-Forwarded-Host and X-Forwarded-Path-Prefix
+// headers of the request, or the defaultURL if the forwarded host is not an API host. The headers are only honoured
+// when the request was sent by one of the trusted proxies. Otherwise, the Builder uses the defaultURL.
+func FromRequestOrDefault(req *http.Request, defaultURL *url.URL, trusted request.TrustedProxies) *Builder {
+	if !trusted.IsTrusted(req) {
+		u := *defaultURL
+		return &Builder{URL: &u}
+	}
+	return fromHeadersOrDefault(&req.Header, defaultURL)
+}
+
+// FromHeadersOrDefault returns a Builder for the URL identified by the X-Forwarded-Host and X-Forwarded-Path-Prefix
+// headers, or the defaultURL if the forwarded host is not an API host. The headers are trusted regardless of who sent
+// them, so any client can change the host and path of the links that are built.
+//
+// Deprecated: use FromRequestOrDefault, which only honours the headers of requests sent by trusted proxies.
 func FromHeadersOrDefault(h *http.Header, defaultURL *url.URL) *Builder {
-	path := h.Get("X-Forwarded-Path-Prefix")
+	return fromHeadersOrDefault(h, defaultURL)
+}
+
+func fromHeadersOrDefault(h *http.Header, defaultURL *url.URL) *Builder {
+	path := h.Get(ForwardedPathPrefixHeader)
 
-	host := h.Get("X-Forwarded-Host")
+	host := h.Get(ForwardedHostHeader)
 	if strings.HasPrefix(host, "api.") {
 		return &Builder{
 			URL: &url.URL{
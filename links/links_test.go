@@ -2,9 +2,11 @@ package links
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"github.com/ONSdigital/dp-net/v3/request"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -351,3 +353,29 @@ func Test_RemovePrefixFromPath(t *testing.T) {
 		}
 	})
 }
+
+func Test_FromRequestOrDefault(t *testing.T) {
+	Convey("Given trusted proxies", t, func() {
+		trusted, err := request.ParseTrustedProxies("10.0.0.0/8")
+		So(err, ShouldBeNil)
+
+		newRequest := func(remoteAddr string) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-Host", "api.external.host")
+			req.Header.Set("X-Forwarded-Path-Prefix", "prefix")
+			return req
+		}
+
+		Convey("Then the forwarded headers of a request from a trusted proxy are honoured", func() {
+			builder := FromRequestOrDefault(newRequest("10.0.0.1:1234"), defaultInternalURL, trusted)
+			So(builder.URL.String(), ShouldEqual, "https://api.external.host/prefix")
+		})
+
+		Convey("Then the forwarded headers of a request from anyone else are ignored", func() {
+			builder := FromRequestOrDefault(newRequest("203.0.113.1:1234"), defaultInternalURL, trusted)
+			So(builder.URL.String(), ShouldEqual, "http://localhost:8080")
+			So(builder.URL, ShouldNotPointTo, defaultInternalURL)
+		})
+	})
+}
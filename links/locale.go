@@ -0,0 +1,119 @@
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+)
+
+// HreflangDefault is the hreflang of the alternate link used for languages with no matching locale
+const HreflangDefault = "x-default"
+
+// LocaleURLs rewrites URLs between locales. Each locale other than the default is identified by a subdomain of the
+// same name, e.g. "cy.ons.gov.uk", or by the first segment of the path if PathPrefix is set, e.g. "/cy/datasets".
+type LocaleURLs struct {
+	Locales request.LocaleConfig
+	// DefaultSubdomain is the subdomain of the default locale, e.g. "www", or empty if it has none
+	DefaultSubdomain string
+	// PathPrefix identifies locales by the first segment of the path rather than the subdomain
+	PathPrefix bool
+}
+
+// DefaultLocaleURLs identifies Welsh pages by the "cy." subdomain, and English pages by the "www." subdomain
+var DefaultLocaleURLs = LocaleURLs{Locales: request.DefaultLocaleConfig, DefaultSubdomain: "www"}
+
+// Alternate is a link to the equivalent page in another language
+type Alternate struct {
+	Hreflang string
+	URL      *url.URL
+}
+
+// LocaleOf returns the locale of the URL, which is the default locale if the URL does not identify a locale
+func (l LocaleURLs) LocaleOf(u *url.URL) string {
+	var segment string
+	if l.PathPrefix {
+		segment, _, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	} else {
+		segment, _, _ = strings.Cut(u.Host, ".")
+	}
+	if locale, ok := l.locale(segment); ok {
+		return locale
+	}
+	return l.Locales.DefaultLocale()
+}
+
+// URLFor returns a copy of the URL rewritten for the locale. The URL must be absolute if locales are identified by
+// subdomain.
+func (l LocaleURLs) URLFor(u *url.URL, locale string) *url.URL {
+	rewritten := *u
+	if l.PathPrefix {
+		path := strings.TrimPrefix(u.Path, "/")
+		if segment, rest, _ := strings.Cut(path, "/"); l.isLocale(segment) {
+			path = rest
+		}
+		rewritten.Path = "/" + path
+		if locale != l.Locales.DefaultLocale() {
+			rewritten.Path = "/" + locale + rewritten.Path
+		}
+		rewritten.RawPath = ""
+		return &rewritten
+	}
+
+	host := u.Host
+	if subdomain, rest, found := strings.Cut(host, "."); found && (l.isLocale(subdomain) || strings.EqualFold(subdomain, l.DefaultSubdomain)) {
+		host = rest
+	}
+	switch {
+	case locale != l.Locales.DefaultLocale():
+		host = strings.ToLower(locale) + "." + host
+	case l.DefaultSubdomain != "":
+		host = l.DefaultSubdomain + "." + host
+	}
+	rewritten.Host = host
+	return &rewritten
+}
+
+// Alternates returns links to the URL in each supported locale, and an "x-default" link to the URL in the default
+// locale, for use as hreflang alternate links
+func (l LocaleURLs) Alternates(u *url.URL) []Alternate {
+	alternates := make([]Alternate, 0, len(l.Locales.Supported)+1)
+	for _, locale := range l.Locales.Supported {
+		alternates = append(alternates, Alternate{Hreflang: locale, URL: l.URLFor(u, locale)})
+	}
+	return append(alternates, Alternate{Hreflang: HreflangDefault, URL: l.URLFor(u, l.Locales.DefaultLocale())})
+}
+
+// AlternateLinkHeader returns the value of a Link header listing the alternates, e.g.
+// `<https://cy.ons.gov.uk/>; rel="alternate"; hreflang="cy"`
+func AlternateLinkHeader(alternates []Alternate) string {
+	links := make([]string, 0, len(alternates))
+	for _, alt := range alternates {
+		links = append(links, fmt.Sprintf(`<%s>; rel="alternate"; hreflang="%s"`, alt.URL, alt.Hreflang))
+	}
+	return strings.Join(links, ", ")
+}
+
+// ForLocale returns a Builder that builds links for the locale
+func (b *Builder) ForLocale(locale string, l LocaleURLs) *Builder {
+	return &Builder{URL: l.URLFor(b.URL, locale)}
+}
+
+// locale returns the supported locale other than the default that exactly matches the URL segment
+func (l LocaleURLs) locale(segment string) (string, bool) {
+	if segment == "" {
+		return "", false
+	}
+	for _, locale := range l.Locales.Supported {
+		if strings.EqualFold(locale, segment) && locale != l.Locales.DefaultLocale() {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+func (l LocaleURLs) isLocale(segment string) bool {
+	_, ok := l.locale(segment)
+	return ok
+}
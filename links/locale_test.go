@@ -0,0 +1,107 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestLocaleURLsSubdomain(t *testing.T) {
+	Convey("Given locales identified by subdomain", t, func() {
+		l := DefaultLocaleURLs
+
+		Convey("Then the locale of a URL is identified by its subdomain", func() {
+			So(l.LocaleOf(mustParse("https://cy.ons.gov.uk/economy")), ShouldEqual, "cy")
+			So(l.LocaleOf(mustParse("https://CY.ons.gov.uk/economy")), ShouldEqual, "cy")
+			So(l.LocaleOf(mustParse("https://www.ons.gov.uk/economy")), ShouldEqual, "en")
+			So(l.LocaleOf(mustParse("http://localhost:20000/economy")), ShouldEqual, "en")
+		})
+
+		Convey("Then URLs are rewritten for the locale", func() {
+			So(l.URLFor(mustParse("https://www.ons.gov.uk/economy?page=2"), "cy").String(), ShouldEqual, "https://cy.ons.gov.uk/economy?page=2")
+			So(l.URLFor(mustParse("https://cy.ons.gov.uk/economy"), "en").String(), ShouldEqual, "https://www.ons.gov.uk/economy")
+			So(l.URLFor(mustParse("https://cy.ons.gov.uk/economy"), "cy").String(), ShouldEqual, "https://cy.ons.gov.uk/economy")
+			So(l.URLFor(mustParse("http://cy.localhost:20000/economy"), "cy").String(), ShouldEqual, "http://cy.localhost:20000/economy")
+		})
+
+		Convey("Then the default locale has no subdomain if DefaultSubdomain is empty", func() {
+			l.DefaultSubdomain = ""
+			So(l.URLFor(mustParse("http://cy.localhost:20000/economy"), "en").String(), ShouldEqual, "http://localhost:20000/economy")
+		})
+
+		Convey("Then the original URL is not modified", func() {
+			u := mustParse("https://www.ons.gov.uk/economy")
+			l.URLFor(u, "cy")
+			So(u.String(), ShouldEqual, "https://www.ons.gov.uk/economy")
+		})
+	})
+}
+
+func TestLocaleURLsPathPrefix(t *testing.T) {
+	Convey("Given locales identified by path prefix", t, func() {
+		l := LocaleURLs{Locales: request.LocaleConfig{Supported: []string{"en", "cy", "fr-CA"}}, PathPrefix: true}
+
+		Convey("Then the locale of a URL is identified by the first segment of its path", func() {
+			So(l.LocaleOf(mustParse("/cy/economy")), ShouldEqual, "cy")
+			So(l.LocaleOf(mustParse("/fr-ca")), ShouldEqual, "fr-CA")
+			So(l.LocaleOf(mustParse("/economy/cy")), ShouldEqual, "en")
+			So(l.LocaleOf(mustParse("/en/economy")), ShouldEqual, "en")
+		})
+
+		Convey("Then URLs are rewritten for the locale", func() {
+			So(l.URLFor(mustParse("/economy?page=2"), "cy").String(), ShouldEqual, "/cy/economy?page=2")
+			So(l.URLFor(mustParse("https://www.ons.gov.uk/cy/economy"), "fr-CA").String(), ShouldEqual, "https://www.ons.gov.uk/fr-CA/economy")
+			So(l.URLFor(mustParse("/cy/economy"), "en").String(), ShouldEqual, "/economy")
+			So(l.URLFor(mustParse("/cy"), "en").String(), ShouldEqual, "/")
+		})
+	})
+}
+
+func TestAlternates(t *testing.T) {
+	Convey("Given a URL", t, func() {
+		u := mustParse("https://www.ons.gov.uk/economy")
+
+		Convey("When the alternates are generated", func() {
+			alternates := DefaultLocaleURLs.Alternates(u)
+
+			Convey("Then there is an alternate for each supported locale, and the default", func() {
+				So(alternates, ShouldHaveLength, 3)
+				So(alternates[0].Hreflang, ShouldEqual, "en")
+				So(alternates[0].URL.String(), ShouldEqual, "https://www.ons.gov.uk/economy")
+				So(alternates[1].Hreflang, ShouldEqual, "cy")
+				So(alternates[1].URL.String(), ShouldEqual, "https://cy.ons.gov.uk/economy")
+				So(alternates[2].Hreflang, ShouldEqual, HreflangDefault)
+				So(alternates[2].URL.String(), ShouldEqual, "https://www.ons.gov.uk/economy")
+			})
+
+			Convey("Then they can be returned in a Link header", func() {
+				So(AlternateLinkHeader(alternates), ShouldEqual,
+					`<https://www.ons.gov.uk/economy>; rel="alternate"; hreflang="en", `+
+						`<https://cy.ons.gov.uk/economy>; rel="alternate"; hreflang="cy", `+
+						`<https://www.ons.gov.uk/economy>; rel="alternate"; hreflang="x-default"`)
+			})
+		})
+	})
+}
+
+func TestBuilderForLocale(t *testing.T) {
+	Convey("Given a Builder", t, func() {
+		b := &Builder{URL: mustParse("https://www.ons.gov.uk/prefix")}
+
+		Convey("Then links built for a locale are on the locale's host", func() {
+			link, err := b.ForLocale("cy", DefaultLocaleURLs).BuildLink("http://localhost:8080/v1/datasets")
+			So(err, ShouldBeNil)
+			So(link, ShouldEqual, "https://cy.ons.gov.uk/prefix/datasets")
+		})
+	})
+}
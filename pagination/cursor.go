@@ -0,0 +1,71 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Cursor errors
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrNoCursorSecret = errors.New("a cursor secret must be configured to use cursors")
+)
+
+// Cursor is the position of a page in a list, identified by the key of an item, such as its id or a sort value.
+// Cursors are given to clients as opaque tokens, which are signed so that they cannot be forged or altered.
+type Cursor struct {
+	// After is the key of the item that the page starts after
+	After string `json:"after,omitempty"`
+	// Before is the key of the item that the page ends before, when paging backwards
+	Before string `json:"before,omitempty"`
+}
+
+// EncodeCursor returns the opaque token of the cursor, signed with the cursor secret
+func (p *Paginator) EncodeCursor(c Cursor) (string, error) {
+	if len(p.cfg.CursorSecret) == 0 {
+		return "", ErrNoCursorSecret
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(p.sign(payload)), nil
+}
+
+// DecodeCursor returns the cursor of a token returned by EncodeCursor. ErrInvalidCursor is returned if the token is
+// malformed or its signature does not match.
+func (p *Paginator) DecodeCursor(token string) (Cursor, error) {
+	if len(p.cfg.CursorSecret) == 0 {
+		return Cursor{}, ErrNoCursorSecret
+	}
+
+	encodedPayload, encodedSig, found := strings.Cut(token, ".")
+	if !found {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, p.sign(payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (p *Paginator) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.cfg.CursorSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
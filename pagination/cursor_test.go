@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testDefaultURL = &url.URL{Scheme: "http", Host: "localhost:8080"}
+
+func TestCursor(t *testing.T) {
+	Convey("Given a paginator with a cursor secret", t, func() {
+		p := New(Config{CursorSecret: []byte("secret"), DefaultURL: testDefaultURL})
+
+		Convey("When a cursor is encoded", func() {
+			token, err := p.EncodeCursor(Cursor{After: "cpih01"})
+			So(err, ShouldBeNil)
+
+			Convey("Then the token is URL safe and decodes to the cursor", func() {
+				So(url.QueryEscape(token), ShouldEqual, token)
+				c, err := p.DecodeCursor(token)
+				So(err, ShouldBeNil)
+				So(c, ShouldResemble, Cursor{After: "cpih01"})
+			})
+
+			Convey("Then the token cannot be decoded with another secret", func() {
+				other := New(Config{CursorSecret: []byte("other"), DefaultURL: testDefaultURL})
+				_, err := other.DecodeCursor(token)
+				So(err, ShouldEqual, ErrInvalidCursor)
+			})
+
+			Convey("Then an altered token cannot be decoded", func() {
+				payload, sig, _ := strings.Cut(token, ".")
+				forged, err := p.EncodeCursor(Cursor{After: "cpih02"})
+				So(err, ShouldBeNil)
+				forgedPayload, _, _ := strings.Cut(forged, ".")
+				So(forgedPayload, ShouldNotEqual, payload)
+
+				_, err = p.DecodeCursor(forgedPayload + "." + sig)
+				So(err, ShouldEqual, ErrInvalidCursor)
+			})
+		})
+
+		Convey("Then malformed tokens cannot be decoded", func() {
+			for _, token := range []string{"", "abc", "abc.def", "!!!.def", "e30.!!!"} {
+				_, err := p.DecodeCursor(token)
+				So(err, ShouldEqual, ErrInvalidCursor)
+			}
+		})
+	})
+
+	Convey("Given a paginator without a cursor secret", t, func() {
+		p := New(Config{DefaultURL: testDefaultURL})
+
+		Convey("Then cursors cannot be encoded or decoded", func() {
+			_, err := p.EncodeCursor(Cursor{After: "cpih01"})
+			So(err, ShouldEqual, ErrNoCursorSecret)
+			_, err = p.DecodeCursor("e30.abc")
+			So(err, ShouldEqual, ErrNoCursorSecret)
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-net/v3/links"
+)
+
+// Link relation constants
+const (
+	RelFirst = "first"
+	RelPrev  = "prev"
+	RelNext  = "next"
+	RelLast  = "last"
+)
+
+// Link is a link to another page of a list
+type Link struct {
+	Rel string
+	URL *url.URL
+}
+
+// Links returns the links to the first, previous, next and last pages of the list, relative to the page of the
+// response. The links are built with links.FromRequestOrDefault, so that they are correct when the request was
+// forwarded with the X-Forwarded-Host and X-Forwarded-Path-Prefix headers. Lists paged by cursor have no last link,
+// and only have previous and next links when the response has their cursors.
+func (p *Paginator) Links(req *http.Request, page Page, resp Response) []Link {
+	builder := links.FromRequestOrDefault(req, p.cfg.DefaultURL, p.cfg.TrustedProxies)
+	link := func(rel string, set func(q url.Values)) Link {
+		u := *req.URL
+		q := u.Query()
+		q.Set(LimitParam, strconv.Itoa(page.Limit))
+		q.Del(OffsetParam)
+		q.Del(CursorParam)
+		set(q)
+		u.RawQuery = q.Encode()
+		return Link{Rel: rel, URL: builder.BuildURL(&u)}
+	}
+
+	if page.Cursor != nil || resp.NextCursor != "" || resp.PrevCursor != "" {
+		result := []Link{link(RelFirst, func(url.Values) {})}
+		if resp.PrevCursor != "" {
+			result = append(result, link(RelPrev, func(q url.Values) { q.Set(CursorParam, resp.PrevCursor) }))
+		}
+		if resp.NextCursor != "" {
+			result = append(result, link(RelNext, func(q url.Values) { q.Set(CursorParam, resp.NextCursor) }))
+		}
+		return result
+	}
+
+	withOffset := func(offset int) func(q url.Values) {
+		return func(q url.Values) { q.Set(OffsetParam, strconv.Itoa(offset)) }
+	}
+	result := []Link{link(RelFirst, withOffset(0))}
+	if page.Limit <= 0 {
+		return result
+	}
+	if page.Offset > 0 {
+		result = append(result, link(RelPrev, withOffset(max(page.Offset-page.Limit, 0))))
+	}
+	if page.Offset+page.Limit < resp.TotalCount {
+		result = append(result, link(RelNext, withOffset(page.Offset+page.Limit)))
+	}
+	if resp.TotalCount > 0 {
+		result = append(result, link(RelLast, withOffset((resp.TotalCount-1)/page.Limit*page.Limit)))
+	}
+	return result
+}
+
+// LinkHeaderValue returns the value of a Link header listing the links, e.g.
+// `<https://api.beta.ons.gov.uk/v1/datasets?limit=20&offset=20>; rel="next"`
+func LinkHeaderValue(pageLinks []Link) string {
+	values := make([]string, 0, len(pageLinks))
+	for _, l := range pageLinks {
+		values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, l.URL, l.Rel))
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,122 @@
+package pagination
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/links"
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func linkURLs(pageLinks []Link) map[string]string {
+	urls := map[string]string{}
+	for _, l := range pageLinks {
+		urls[l.Rel] = l.URL.String()
+	}
+	return urls
+}
+
+func TestLinks(t *testing.T) {
+	Convey("Given a paginator", t, func() {
+		trusted, err := request.ParseTrustedProxies("10.0.0.0/8")
+		So(err, ShouldBeNil)
+		p := New(Config{CursorSecret: []byte("secret"), DefaultURL: testDefaultURL, TrustedProxies: trusted})
+		req := httptest.NewRequest(http.MethodGet, "/datasets?state=published&limit=10", http.NoBody)
+
+		Convey("When the links of the first page of a list are built", func() {
+			urls := linkURLs(p.Links(req, Page{Limit: 10}, Response{TotalCount: 25}))
+
+			Convey("Then there are first, next and last links which keep the other query parameters", func() {
+				So(urls, ShouldResemble, map[string]string{
+					RelFirst: "http://localhost:8080/datasets?limit=10&offset=0&state=published",
+					RelNext:  "http://localhost:8080/datasets?limit=10&offset=10&state=published",
+					RelLast:  "http://localhost:8080/datasets?limit=10&offset=20&state=published",
+				})
+			})
+		})
+
+		Convey("When the links of the last page of a list are built", func() {
+			urls := linkURLs(p.Links(req, Page{Limit: 10, Offset: 20}, Response{TotalCount: 25}))
+
+			Convey("Then there are first, prev and last links", func() {
+				So(urls, ShouldResemble, map[string]string{
+					RelFirst: "http://localhost:8080/datasets?limit=10&offset=0&state=published",
+					RelPrev:  "http://localhost:8080/datasets?limit=10&offset=10&state=published",
+					RelLast:  "http://localhost:8080/datasets?limit=10&offset=20&state=published",
+				})
+			})
+		})
+
+		Convey("When the links of an empty list are built", func() {
+			urls := linkURLs(p.Links(req, Page{Limit: 10}, Response{}))
+
+			Convey("Then there is only a first link", func() {
+				So(urls, ShouldHaveLength, 1)
+				So(urls, ShouldContainKey, RelFirst)
+			})
+		})
+
+		Convey("When the links of a page with a zero limit are built", func() {
+			urls := linkURLs(p.Links(req, Page{Offset: 10}, Response{TotalCount: 25}))
+
+			Convey("Then there is only a first link", func() {
+				So(urls, ShouldResemble, map[string]string{
+					RelFirst: "http://localhost:8080/datasets?limit=0&offset=0&state=published",
+				})
+			})
+		})
+
+		Convey("When the links of a cursor page are built", func() {
+			urls := linkURLs(p.Links(req, Page{Limit: 10, Cursor: &Cursor{After: "a"}}, Response{PrevCursor: "prev", NextCursor: "next"}))
+
+			Convey("Then there are first, prev and next links, but no last link", func() {
+				So(urls, ShouldResemble, map[string]string{
+					RelFirst: "http://localhost:8080/datasets?limit=10&state=published",
+					RelPrev:  "http://localhost:8080/datasets?cursor=prev&limit=10&state=published",
+					RelNext:  "http://localhost:8080/datasets?cursor=next&limit=10&state=published",
+				})
+			})
+		})
+
+		Convey("When a request forwarded by a trusted proxy has a path prefix", func() {
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set(links.ForwardedHostHeader, "api.beta.ons.gov.uk")
+			req.Header.Set(links.ForwardedPathPrefixHeader, "/v1")
+			urls := linkURLs(p.Links(req, Page{Limit: 10}, Response{TotalCount: 15}))
+
+			Convey("Then the links are built for the forwarded URL", func() {
+				So(urls[RelNext], ShouldEqual, "https://api.beta.ons.gov.uk/v1/datasets?limit=10&offset=10&state=published")
+			})
+		})
+
+		Convey("When a request from an untrusted client has a path prefix", func() {
+			req.Header.Set(links.ForwardedHostHeader, "api.evil.com")
+			req.Header.Set(links.ForwardedPathPrefixHeader, "/v1")
+			urls := linkURLs(p.Links(req, Page{Limit: 10}, Response{TotalCount: 15}))
+
+			Convey("Then the forwarded headers are ignored", func() {
+				So(urls[RelNext], ShouldEqual, "http://localhost:8080/datasets?limit=10&offset=10&state=published")
+			})
+		})
+	})
+}
+
+func TestLinkHeaderValue(t *testing.T) {
+	Convey("Given links to pages", t, func() {
+		p := New(Config{DefaultURL: testDefaultURL})
+		req := httptest.NewRequest(http.MethodGet, "/datasets", http.NoBody)
+		pageLinks := p.Links(req, Page{Limit: 10}, Response{TotalCount: 15})
+
+		Convey("Then the Link header lists each link with its relation", func() {
+			So(LinkHeaderValue(pageLinks), ShouldEqual, `<http://localhost:8080/datasets?limit=10&offset=0>; rel="first", `+
+				`<http://localhost:8080/datasets?limit=10&offset=10>; rel="next", `+
+				`<http://localhost:8080/datasets?limit=10&offset=10>; rel="last"`)
+		})
+
+		Convey("Then no links is an empty header", func() {
+			So(LinkHeaderValue(nil), ShouldBeEmpty)
+		})
+	})
+}
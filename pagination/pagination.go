@@ -0,0 +1,202 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-net/v3/responder"
+)
+
+// Query parameter constants
+const (
+	LimitParam  = "limit"
+	OffsetParam = "offset"
+	CursorParam = "cursor"
+)
+
+// LinkHeader is the header of the links to other pages of a list (RFC 8288)
+const LinkHeader = "Link"
+
+// Default page size constants
+const (
+	DefaultLimit    = 20
+	DefaultMaxLimit = 1000
+)
+
+// Config is the configuration of a Paginator
+type Config struct {
+	// DefaultLimit is the limit of pages when no limit is requested. Zero uses DefaultLimit.
+	DefaultLimit int
+	// MaxLimit is the largest limit that can be requested. Zero uses DefaultMaxLimit.
+	MaxLimit int
+	// CursorSecret is the key that cursor tokens are signed with. Cursors cannot be used if it is empty.
+	CursorSecret []byte
+	// DefaultURL is the URL that links are built from, unless the request was forwarded by a trusted proxy, see
+	// links.FromRequestOrDefault
+	DefaultURL *url.URL
+	// TrustedProxies are trusted to set the X-Forwarded-Host and X-Forwarded-Path-Prefix headers
+	TrustedProxies request.TrustedProxies
+}
+
+// Paginator parses the page requested from a list, and responds with the page and links to the other pages
+type Paginator struct {
+	cfg     Config
+	respond *responder.Responder
+}
+
+// New returns a new Paginator
+func New(cfg Config) *Paginator {
+	if cfg.DefaultURL == nil {
+		panic("pagination: a DefaultURL must be provided")
+	}
+	if cfg.DefaultLimit <= 0 {
+		cfg.DefaultLimit = DefaultLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxLimit
+	}
+	return &Paginator{cfg: cfg, respond: responder.New()}
+}
+
+// Page is the page of a list that was requested, which is identified by either its offset or a cursor
+type Page struct {
+	Limit  int
+	Offset int
+	// Cursor is the cursor of the page, or nil if the page is identified by its offset
+	Cursor *Cursor
+}
+
+type pageParams struct {
+	Limit  *int   `query:"limit" min:"0"`
+	Offset *int   `query:"offset" min:"0"`
+	Cursor string `query:"cursor"`
+}
+
+// Parse returns the page requested by the limit and offset, or limit and cursor, query parameters of the request. If
+// any parameters are not valid, request.FieldErrors is returned with every invalid parameter, which can be rendered
+// with responder.Errors.
+func (p *Paginator) Parse(req *http.Request) (Page, error) {
+	var params pageParams
+	var errs request.FieldErrors
+	if err := request.BindQueryRequest(req, &params); err != nil {
+		if !errors.As(err, &errs) {
+			return Page{}, err
+		}
+	}
+
+	page := Page{Limit: p.cfg.DefaultLimit}
+	if params.Limit != nil {
+		page.Limit = *params.Limit
+		if page.Limit > p.cfg.MaxLimit {
+			errs = append(errs, &request.FieldError{
+				Param: LimitParam,
+				Value: strconv.Itoa(page.Limit),
+				Err:   fmt.Errorf("must be at most %d", p.cfg.MaxLimit),
+			})
+		}
+	}
+	if params.Offset != nil {
+		page.Offset = *params.Offset
+	}
+
+	if params.Cursor != "" {
+		if params.Offset != nil {
+			errs = append(errs, &request.FieldError{
+				Param: OffsetParam,
+				Value: strconv.Itoa(page.Offset),
+				Err:   fmt.Errorf("cannot be used with a %s", CursorParam),
+			})
+		}
+		c, err := p.DecodeCursor(params.Cursor)
+		if err != nil {
+			errs = append(errs, &request.FieldError{Param: CursorParam, Value: params.Cursor, Err: err})
+		}
+		page.Cursor = &c
+	}
+
+	if len(errs) > 0 {
+		return Page{}, errs
+	}
+	return page, nil
+}
+
+// Result is a page of a list to respond with
+type Result struct {
+	// Items are the items of the page, which must be a slice
+	Items interface{}
+	// TotalCount is the number of items in the whole list
+	TotalCount int
+	// Next is the cursor of the next page, or nil if there is no next page or the list is paged by offset
+	Next *Cursor
+	// Prev is the cursor of the previous page, or nil if there is no previous page or the list is paged by offset
+	Prev *Cursor
+}
+
+// Response is the JSON body of a paginated response
+type Response struct {
+	Count      int         `json:"count"`
+	Offset     int         `json:"offset"`
+	Limit      int         `json:"limit"`
+	TotalCount int         `json:"total_count"`
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+}
+
+// NewResponse returns the JSON body of the page of results
+func (p *Paginator) NewResponse(page Page, result Result) (Response, error) {
+	resp := Response{
+		Offset:     page.Offset,
+		Limit:      page.Limit,
+		TotalCount: result.TotalCount,
+		Items:      result.Items,
+	}
+	if page.Cursor != nil {
+		resp.Offset = 0
+	}
+
+	items := reflect.ValueOf(result.Items)
+	switch {
+	case !items.IsValid() || (items.Kind() == reflect.Slice && items.IsNil()):
+		resp.Items = []interface{}{}
+	case items.Kind() == reflect.Slice || items.Kind() == reflect.Array:
+		resp.Count = items.Len()
+	default:
+		return Response{}, fmt.Errorf("paginated items must be a slice, not %T", result.Items)
+	}
+
+	var err error
+	if result.Next != nil {
+		if resp.NextCursor, err = p.EncodeCursor(*result.Next); err != nil {
+			return Response{}, fmt.Errorf("failed to encode next cursor: %w", err)
+		}
+	}
+	if result.Prev != nil {
+		if resp.PrevCursor, err = p.EncodeCursor(*result.Prev); err != nil {
+			return Response{}, fmt.Errorf("failed to encode prev cursor: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// Respond responds to the request with the page of results, as a Response, and a Link header with links to the
+// first, previous, next and last pages
+func (p *Paginator) Respond(w http.ResponseWriter, req *http.Request, page Page, result Result) {
+	ctx := req.Context()
+
+	resp, err := p.NewResponse(page, result)
+	if err != nil {
+		p.respond.Error(ctx, w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if header := LinkHeaderValue(p.Links(req, page, resp)); header != "" {
+		w.Header().Set(LinkHeader, header)
+	}
+	p.respond.JSON(ctx, w, http.StatusOK, resp)
+}
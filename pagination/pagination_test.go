@@ -0,0 +1,140 @@
+package pagination
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNew(t *testing.T) {
+	Convey("Given a config without limits", t, func() {
+		p := New(Config{DefaultURL: testDefaultURL})
+
+		Convey("Then the default limits are used", func() {
+			So(p.cfg.DefaultLimit, ShouldEqual, DefaultLimit)
+			So(p.cfg.MaxLimit, ShouldEqual, DefaultMaxLimit)
+		})
+	})
+
+	Convey("Given a config without a default URL", t, func() {
+		Convey("Then New panics", func() {
+			So(func() { New(Config{}) }, ShouldPanic)
+		})
+	})
+}
+
+func TestParse(t *testing.T) {
+	Convey("Given a paginator with a max limit of 100", t, func() {
+		p := New(Config{DefaultLimit: 10, MaxLimit: 100, CursorSecret: []byte("secret"), DefaultURL: testDefaultURL})
+		parse := func(query string) (Page, error) {
+			return p.Parse(httptest.NewRequest(http.MethodGet, "/datasets"+query, http.NoBody))
+		}
+
+		Convey("When no parameters are given, then the first page with the default limit is returned", func() {
+			page, err := parse("")
+			So(err, ShouldBeNil)
+			So(page, ShouldResemble, Page{Limit: 10})
+		})
+
+		Convey("When a limit and offset are given, then they are returned", func() {
+			page, err := parse("?limit=50&offset=100")
+			So(err, ShouldBeNil)
+			So(page, ShouldResemble, Page{Limit: 50, Offset: 100})
+		})
+
+		Convey("When a cursor is given, then the cursor is returned", func() {
+			token, err := p.EncodeCursor(Cursor{After: "cpih01"})
+			So(err, ShouldBeNil)
+
+			page, err := parse("?limit=5&cursor=" + token)
+			So(err, ShouldBeNil)
+			So(page, ShouldResemble, Page{Limit: 5, Cursor: &Cursor{After: "cpih01"}})
+		})
+
+		Convey("When every parameter is invalid, then every error is returned", func() {
+			_, err := parse("?limit=101&offset=-1&cursor=abc")
+
+			var errs request.FieldErrors
+			So(errors.As(err, &errs), ShouldBeTrue)
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Param, ShouldEqual, OffsetParam)
+			So(errs[1].Error(), ShouldEqual, "invalid query parameter 'limit' value '101': must be at most 100")
+			So(errs[2].Param, ShouldEqual, CursorParam)
+			So(errs[2].Err, ShouldEqual, ErrInvalidCursor)
+		})
+
+		Convey("When an offset is given with a cursor, then an error is returned", func() {
+			token, err := p.EncodeCursor(Cursor{After: "cpih01"})
+			So(err, ShouldBeNil)
+
+			_, err = parse("?offset=10&cursor=" + token)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid query parameter 'offset' value '10': cannot be used with a cursor")
+		})
+
+		Convey("When a limit is not a number, then an error is returned", func() {
+			_, err := parse("?limit=ten")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid query parameter 'limit' value 'ten': must be an integer")
+		})
+	})
+}
+
+func TestRespond(t *testing.T) {
+	Convey("Given a paginator", t, func() {
+		p := New(Config{CursorSecret: []byte("secret"), DefaultURL: testDefaultURL})
+		req := httptest.NewRequest(http.MethodGet, "/datasets?limit=2&offset=2", http.NoBody)
+
+		Convey("When it responds with a page of an offset list", func() {
+			w := httptest.NewRecorder()
+			p.Respond(w, req, Page{Limit: 2, Offset: 2}, Result{Items: []string{"a", "b"}, TotalCount: 5})
+
+			Convey("Then the paginated response and links are written", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, `{"count":2,"offset":2,"limit":2,"total_count":5,"items":["a","b"]}`)
+				So(w.Header().Get(LinkHeader), ShouldEqual, `<http://localhost:8080/datasets?limit=2&offset=0>; rel="first", `+
+					`<http://localhost:8080/datasets?limit=2&offset=0>; rel="prev", `+
+					`<http://localhost:8080/datasets?limit=2&offset=4>; rel="next", `+
+					`<http://localhost:8080/datasets?limit=2&offset=4>; rel="last"`)
+			})
+		})
+
+		Convey("When it responds with no items", func() {
+			w := httptest.NewRecorder()
+			p.Respond(w, req, Page{Limit: 2, Offset: 2}, Result{})
+
+			Convey("Then the items are an empty array", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, `{"count":0,"offset":2,"limit":2,"total_count":0,"items":[]}`)
+			})
+		})
+
+		Convey("When it responds with a page of a cursor list", func() {
+			w := httptest.NewRecorder()
+			p.Respond(w, req, Page{Limit: 2}, Result{Items: []string{"a", "b"}, TotalCount: 5, Next: &Cursor{After: "b"}})
+
+			Convey("Then the next cursor is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				next, err := p.EncodeCursor(Cursor{After: "b"})
+				So(err, ShouldBeNil)
+				So(w.Body.String(), ShouldEqual, `{"count":2,"offset":0,"limit":2,"total_count":5,"items":["a","b"],"next_cursor":"`+next+`"}`)
+				So(w.Header().Get(LinkHeader), ShouldEqual, `<http://localhost:8080/datasets?limit=2>; rel="first", `+
+					`<http://localhost:8080/datasets?cursor=`+next+`&limit=2>; rel="next"`)
+			})
+		})
+
+		Convey("When it responds with items that are not a slice", func() {
+			w := httptest.NewRecorder()
+			p.Respond(w, req, Page{Limit: 2}, Result{Items: "a"})
+
+			Convey("Then an internal server error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusInternalServerError)
+				So(w.Header().Get(LinkHeader), ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,179 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Header constants
+const (
+	ForwardedHeader    = "Forwarded"
+	ForwardedForHeader = "X-Forwarded-For"
+)
+
+// TrustedProxies are the IP ranges of the proxies, such as CDNs and load balancers, whose forwarded headers are
+// trusted to identify the client of a request
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses IP addresses and CIDRs, e.g. "10.0.0.0/8" or "192.168.0.1", into TrustedProxies
+func ParseTrustedProxies(values ...string) (TrustedProxies, error) {
+	trusted := make(TrustedProxies, 0, len(values))
+	for _, v := range values {
+		if strings.Contains(v, "/") {
+			_, ipNet, err := net.ParseCIDR(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy CIDR '%s': %w", v, err)
+			}
+			trusted = append(trusted, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted proxy IP address '%s'", v)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return trusted, nil
+}
+
+// Contains returns true if the IP address is one of the trusted proxies
+func (t TrustedProxies) Contains(ip net.IP) bool {
+	for _, ipNet := range t {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrusted returns true if the request was sent directly by one of the trusted proxies
+func (t TrustedProxies) IsTrusted(req *http.Request) bool {
+	ip := parseRemoteIP(req.RemoteAddr)
+	return ip != nil && t.Contains(ip)
+}
+
+// ClientIP returns the IP address of the client that sent the request, trusting the X-Forwarded-For header of the
+// trusted proxies, see ClientIPConfig.ClientIP
+func (t TrustedProxies) ClientIP(req *http.Request) string {
+	return ClientIPConfig{TrustedProxies: t}.ClientIP(req)
+}
+
+// ClientIPConfig is the configuration of how the IP address of the client that sent a request is resolved
+type ClientIPConfig struct {
+	TrustedProxies TrustedProxies
+	// Header is the one header that the trusted proxies append the addresses they forward for, either ForwardedHeader
+	// (RFC 7239) or a header listing addresses such as ForwardedForHeader. Any other forwarded header is ignored, as
+	// it could have been set by the client. Empty uses ForwardedForHeader.
+	Header string
+}
+
+// ClientIP returns the IP address of the client that sent the request. If the request was sent by a trusted proxy,
+// the addresses in the configured header are walked from right to left to find the first address that is not a
+// trusted proxy. If every address is a trusted proxy, or an address is not valid, the last valid address is returned.
+// An empty string is returned if the remote address of the request is not valid.
+func (c ClientIPConfig) ClientIP(req *http.Request) string {
+	ip := parseRemoteIP(req.RemoteAddr)
+	if ip == nil {
+		return ""
+	}
+	if !c.TrustedProxies.Contains(ip) {
+		return ip.String()
+	}
+
+	hops := c.forwardedFor(req.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseForwardedNode(hops[i])
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !c.TrustedProxies.Contains(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// forwardedFor returns the 'for' parameters of each element of the header if it is the Forwarded header, or else the
+// addresses listed in the header
+func (c ClientIPConfig) forwardedFor(header http.Header) []string {
+	name := c.Header
+	if name == "" {
+		name = ForwardedForHeader
+	}
+	values := strings.Split(strings.Join(header.Values(name), ","), ",")
+	if http.CanonicalHeaderKey(name) != ForwardedHeader {
+		return values
+	}
+
+	hops := make([]string, 0, len(values))
+	for _, element := range values {
+		// an element without a 'for' parameter cannot be resolved, so is an empty hop
+		var hop string
+		for _, pair := range strings.Split(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(key, "for") {
+				hop = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseForwardedNode parses a node of a forwarded header, which may be quoted and include a port, e.g.
+// "[2001:db8::1]:4711". Obfuscated and unknown nodes are not valid.
+func parseForwardedNode(node string) net.IP {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if ip := net.ParseIP(node); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+}
+
+func parseRemoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ClientIP gets the IP address of the client that sent the request, as stored in the request context by
+// HandlerClientIP or HandlerInfo. If it is not stored, the remote address of the request is returned, trusting no
+// proxies.
+func ClientIP(req *http.Request) string {
+	if ip := GetInfo(req.Context()).ClientIP; ip != "" {
+		return ip
+	}
+	return TrustedProxies(nil).ClientIP(req)
+}
+
+// SetClientIP sets the client IP address on the context
+func SetClientIP(ctx context.Context, ip string) context.Context {
+	return updateInfo(ctx, func(info *Info) {
+		info.ClientIP = ip
+	})
+}
+
+// HandlerClientIP is a middleware that resolves the IP address of the client that sent each request, trusting the
+// configured forwarded header of the trusted proxies, and stores it in the request context. See
+// ClientIPConfig.ClientIP.
+func HandlerClientIP(cfg ClientIPConfig) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h.ServeHTTP(w, req.WithContext(SetClientIP(req.Context(), cfg.ClientIP(req))))
+		})
+	}
+}
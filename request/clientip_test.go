@@ -0,0 +1,150 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	Convey("IP addresses and CIDRs are parsed", t, func() {
+		trusted, err := ParseTrustedProxies("10.0.0.0/8", "192.168.0.1", "2001:db8::/32")
+		So(err, ShouldBeNil)
+		So(trusted, ShouldHaveLength, 3)
+		So(trusted[1].String(), ShouldEqual, "192.168.0.1/32")
+	})
+
+	Convey("Invalid values return an error", t, func() {
+		_, err := ParseTrustedProxies("10.0.0.0/8", "not-an-ip")
+		So(err, ShouldNotBeNil)
+		_, err = ParseTrustedProxies("10.0.0.0/33")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8", "2001:db8::/32")
+
+	newRequest := func(remoteAddr string, header http.Header) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		return req
+	}
+
+	Convey("Forwarded headers from an untrusted remote address are ignored", t, func() {
+		req := newRequest("203.0.113.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}})
+		So(trusted.ClientIP(req), ShouldEqual, "203.0.113.1")
+	})
+
+	Convey("X-Forwarded-For is walked from right to left to the first untrusted address", t, func() {
+		req := newRequest("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.9, 198.51.100.1", "10.0.0.2"}})
+		So(trusted.ClientIP(req), ShouldEqual, "198.51.100.1")
+	})
+
+	Convey("The last valid address is used if an address is invalid or all are trusted", t, func() {
+		req := newRequest("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.9, garbage, 10.0.0.3"}})
+		So(trusted.ClientIP(req), ShouldEqual, "10.0.0.3")
+
+		req = newRequest("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"10.0.0.4, 10.0.0.3"}})
+		So(trusted.ClientIP(req), ShouldEqual, "10.0.0.4")
+
+		req = newRequest("10.0.0.1:1234", nil)
+		So(trusted.ClientIP(req), ShouldEqual, "10.0.0.1")
+	})
+
+	Convey("A Forwarded header set by the client is ignored when walking X-Forwarded-For", t, func() {
+		req := newRequest("10.0.0.5:1234", http.Header{
+			"Forwarded":       {"for=198.51.100.1"},
+			"X-Forwarded-For": {"203.0.113.9"},
+		})
+		So(trusted.ClientIP(req), ShouldEqual, "203.0.113.9")
+	})
+
+	Convey("An invalid remote address returns an empty string", t, func() {
+		So(trusted.ClientIP(newRequest("invalid", nil)), ShouldEqual, "")
+	})
+
+	Convey("IsTrusted checks the remote address of the request", t, func() {
+		So(trusted.IsTrusted(newRequest("10.1.2.3:1234", nil)), ShouldBeTrue)
+		So(trusted.IsTrusted(newRequest("[2001:db8::5]:1234", nil)), ShouldBeTrue)
+		So(trusted.IsTrusted(newRequest("203.0.113.1:1234", nil)), ShouldBeFalse)
+	})
+}
+
+func TestClientIPConfigClientIP(t *testing.T) {
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8", "2001:db8::/32")
+	forwarded := ClientIPConfig{TrustedProxies: trusted, Header: ForwardedHeader}
+
+	newRequest := func(remoteAddr string, header http.Header) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		return req
+	}
+
+	Convey("The Forwarded header is walked when it is the configured header", t, func() {
+		req := newRequest("10.0.0.1:1234", http.Header{
+			"Forwarded": {`for=198.51.100.9;proto=https, for="[2001:db8::1]:4711"`, `For="198.51.100.2:80";by=10.0.0.1`},
+		})
+		So(forwarded.ClientIP(req), ShouldEqual, "198.51.100.2")
+
+		req = newRequest("10.0.0.1:1234", http.Header{"Forwarded": {`for=198.51.100.9, for="[2001:db8::1]:4711"`}})
+		So(forwarded.ClientIP(req), ShouldEqual, "198.51.100.9")
+	})
+
+	Convey("An X-Forwarded-For header set by the client is ignored when walking Forwarded", t, func() {
+		req := newRequest("10.0.0.1:1234", http.Header{
+			"Forwarded":       {"for=203.0.113.9"},
+			"X-Forwarded-For": {"198.51.100.1"},
+		})
+		So(forwarded.ClientIP(req), ShouldEqual, "203.0.113.9")
+	})
+
+	Convey("Obfuscated and missing Forwarded nodes are not valid", t, func() {
+		req := newRequest("10.0.0.1:1234", http.Header{"Forwarded": {"for=198.51.100.9, for=_hidden"}})
+		So(forwarded.ClientIP(req), ShouldEqual, "10.0.0.1")
+
+		req = newRequest("10.0.0.1:1234", http.Header{"Forwarded": {"for=198.51.100.9, proto=https"}})
+		So(forwarded.ClientIP(req), ShouldEqual, "10.0.0.1")
+	})
+
+	Convey("Another header listing addresses can be configured", t, func() {
+		cfg := ClientIPConfig{TrustedProxies: trusted, Header: "X-Real-Ip"}
+		req := newRequest("10.0.0.1:1234", http.Header{
+			"X-Real-Ip":       {"203.0.113.9"},
+			"X-Forwarded-For": {"198.51.100.1"},
+		})
+		So(cfg.ClientIP(req), ShouldEqual, "203.0.113.9")
+	})
+}
+func TestHandlerClientIP(t *testing.T) {
+	Convey("Given a handler wrapped by the HandlerClientIP middleware", t, func() {
+		trusted, _ := ParseTrustedProxies("10.0.0.0/8")
+		var clientIP string
+		handler := HandlerClientIP(ClientIPConfig{TrustedProxies: trusted})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			clientIP = ClientIP(req)
+		}))
+
+		Convey("Then the resolved client IP is stored in the request context", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			So(clientIP, ShouldEqual, "198.51.100.1")
+		})
+	})
+
+	Convey("Without the middleware, ClientIP returns the remote address", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		So(ClientIP(req), ShouldEqual, "10.0.0.1")
+	})
+}
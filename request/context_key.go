@@ -9,4 +9,5 @@ const (
 	FlorenceIdentityKey    = ContextKey("florence-id")
 	LocaleContextKey       = ContextKey(LocaleHeaderKey)
 	CollectionIDContextKey = ContextKey(CollectionIDHeaderKey)
+	CSPNonceContextKey     = ContextKey("csp-nonce")
 )
@@ -74,18 +74,17 @@ func AddDownloadServiceTokenHeader(r *http.Request, serviceToken string) {
 
 // User gets the user identity from the context
 func User(ctx context.Context) string {
-	userIdentity, _ := ctx.Value(UserIdentityKey).(string)
-	return userIdentity
+	return GetInfo(ctx).User
 }
 
 // SetUser sets the user identity on the context
 func SetUser(ctx context.Context, user string) context.Context {
-	return context.WithValue(ctx, UserIdentityKey, user)
+	return withInfoValue(ctx, UserIdentityKey, user)
 }
 
 // SetFlorenceIdentity sets the florence identity for authentication
 func SetFlorenceIdentity(ctx context.Context, user string) context.Context {
-	return context.WithValue(ctx, FlorenceIdentityKey, user)
+	return withInfoValue(ctx, FlorenceIdentityKey, user)
 }
 
 // SetFlorenceHeader sets a florence Header if the corresponding Identity key is in context
@@ -127,11 +126,10 @@ func IsCallerPresent(ctx context.Context) bool {
 
 // Caller gets the caller identity from the context
 func Caller(ctx context.Context) string {
-	callerIdentity, _ := ctx.Value(CallerIdentityKey).(string)
-	return callerIdentity
+	return GetInfo(ctx).Caller
 }
 
 // SetCaller sets the caller identity on the context
 func SetCaller(ctx context.Context, caller string) context.Context {
-	return context.WithValue(ctx, CallerIdentityKey, caller)
+	return withInfoValue(ctx, CallerIdentityKey, caller)
 }
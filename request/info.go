@@ -0,0 +1,163 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDSize is the length of the request IDs generated by HandlerInfo
+const DefaultRequestIDSize = 16
+
+// Info is the information about a request that is stored in its context, see GetInfo
+type Info struct {
+	RequestID     string
+	Caller        string
+	User          string
+	FlorenceToken string
+	Locale        string
+	CollectionID  string
+	ClientIP      string
+	StartTime     time.Time
+}
+
+// Elapsed returns the time since the request started, or zero if the start time is not known
+func (info Info) Elapsed() time.Duration {
+	if info.StartTime.IsZero() {
+		return 0
+	}
+	return time.Since(info.StartTime)
+}
+
+// infoKey is the context key of the Info, which is unexported so that it cannot collide with other keys
+type infoKey struct{}
+
+// legacyKeys are the context keys of the values of Info that are also stored individually, so that code reading them
+// with ctx.Value(key) continues to work
+var legacyKeys = []struct {
+	key   ContextKey
+	field func(*Info) *string
+}{
+	{RequestIdKey, func(info *Info) *string { return &info.RequestID }},
+	{CallerIdentityKey, func(info *Info) *string { return &info.Caller }},
+	{UserIdentityKey, func(info *Info) *string { return &info.User }},
+	{FlorenceIdentityKey, func(info *Info) *string { return &info.FlorenceToken }},
+	{LocaleContextKey, func(info *Info) *string { return &info.Locale }},
+	{CollectionIDContextKey, func(info *Info) *string { return &info.CollectionID }},
+}
+
+// GetInfo gets the information about the request from the context. Values stored under the individual context keys,
+// e.g. CallerIdentityKey, take precedence, as they may have been set after the Info.
+func GetInfo(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	for _, legacy := range legacyKeys {
+		if value, ok := ctx.Value(legacy.key).(string); ok && value != "" {
+			*legacy.field(&info) = value
+		}
+	}
+	return info
+}
+
+// WithInfo sets the information about the request on the context, including setting each non-empty value under its
+// individual context key
+func WithInfo(ctx context.Context, info Info) context.Context {
+	ctx = context.WithValue(ctx, infoKey{}, info)
+	for _, legacy := range legacyKeys {
+		if value := *legacy.field(&info); value != "" {
+			ctx = context.WithValue(ctx, legacy.key, value)
+		}
+	}
+	return ctx
+}
+
+// updateInfo updates the Info on the context
+func updateInfo(ctx context.Context, update func(*Info)) context.Context {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	update(&info)
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// withInfoValue sets a single value of the Info on the context, and under its individual context key
+func withInfoValue(ctx context.Context, key ContextKey, value string) context.Context {
+	ctx = updateInfo(ctx, func(info *Info) {
+		for _, legacy := range legacyKeys {
+			if legacy.key == key {
+				*legacy.field(info) = value
+			}
+		}
+	})
+	return context.WithValue(ctx, key, value)
+}
+
+// SetLocale sets the locale on the context
+func SetLocale(ctx context.Context, locale string) context.Context {
+	return withInfoValue(ctx, LocaleContextKey, locale)
+}
+
+// Locale gets the locale from the context
+func Locale(ctx context.Context) string {
+	return GetInfo(ctx).Locale
+}
+
+// SetCollectionID sets the collection id on the context
+func SetCollectionID(ctx context.Context, collectionID string) context.Context {
+	return withInfoValue(ctx, CollectionIDContextKey, collectionID)
+}
+
+// CollectionID gets the collection id from the context
+func CollectionID(ctx context.Context) string {
+	return GetInfo(ctx).CollectionID
+}
+
+// FlorenceIdentity gets the florence identity from the context
+func FlorenceIdentity(ctx context.Context) string {
+	return GetInfo(ctx).FlorenceToken
+}
+
+// InfoConfig is the configuration of HandlerInfo
+type InfoConfig struct {
+	// RequestIDSize is the length of generated request IDs. Zero uses DefaultRequestIDSize.
+	RequestIDSize int
+	// Locales are the locales negotiated with NegotiateLocale. If no locales are supported, DefaultLocaleConfig is used.
+	Locales LocaleConfig
+	// ClientIP configures which proxies and forwarded header are trusted to identify the client IP address
+	ClientIP ClientIPConfig
+}
+
+// HandlerInfo is a middleware that populates the Info of each request, and stores it in the request context. The
+// request ID is taken from the X-Request-Id header, which is added if missing, as HandlerRequestID. The caller and
+// user are not known until the request has been authenticated, and are set by the identity middleware.
+func HandlerInfo(cfg InfoConfig) func(http.Handler) http.Handler {
+	if cfg.RequestIDSize <= 0 {
+		cfg.RequestIDSize = DefaultRequestIDSize
+	}
+	if len(cfg.Locales.Supported) == 0 {
+		cfg.Locales = DefaultLocaleConfig
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			info := Info{
+				RequestID: req.Header.Get(RequestHeaderKey),
+				Locale:    NegotiateLocale(req, cfg.Locales),
+				ClientIP:  cfg.ClientIP.ClientIP(req),
+				StartTime: time.Now(),
+			}
+
+			if info.RequestID == "" {
+				info.RequestID = NewRequestID(cfg.RequestIDSize)
+				AddRequestIdHeader(req, info.RequestID)
+			}
+
+			info.FlorenceToken = req.Header.Get(FlorenceHeaderKey)
+			if c, err := req.Cookie(FlorenceCookieKey); info.FlorenceToken == "" && err == nil {
+				info.FlorenceToken = c.Value
+			}
+
+			// an error means that the collection id is not present
+			info.CollectionID, _ = GetCollectionID(req)
+
+			h.ServeHTTP(w, req.WithContext(WithInfo(req.Context(), info)))
+		})
+	}
+}
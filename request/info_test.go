@@ -0,0 +1,125 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInfo(t *testing.T) {
+	Convey("Given a context with no Info", t, func() {
+		ctx := context.Background()
+
+		Convey("Then GetInfo returns an empty Info", func() {
+			So(GetInfo(ctx), ShouldResemble, Info{})
+			So(GetInfo(ctx).Elapsed(), ShouldEqual, 0)
+		})
+
+		Convey("Then values set under the individual context keys are returned", func() {
+			ctx = context.WithValue(ctx, CallerIdentityKey, "caller")
+			ctx = context.WithValue(ctx, LocaleContextKey, "cy")
+			So(GetInfo(ctx), ShouldResemble, Info{Caller: "caller", Locale: "cy"})
+		})
+	})
+
+	Convey("Given a context with Info", t, func() {
+		start := time.Now().Add(-time.Second)
+		ctx := WithInfo(context.Background(), Info{
+			RequestID:     "request-123",
+			FlorenceToken: "florence-token",
+			Locale:        "en",
+			CollectionID:  "collection-1",
+			ClientIP:      "10.1.2.3",
+			StartTime:     start,
+		})
+
+		Convey("Then the Info is returned by GetInfo", func() {
+			info := GetInfo(ctx)
+			So(info.RequestID, ShouldEqual, "request-123")
+			So(info.ClientIP, ShouldEqual, "10.1.2.3")
+			So(info.StartTime, ShouldEqual, start)
+			So(info.Elapsed(), ShouldBeGreaterThanOrEqualTo, time.Second)
+		})
+
+		Convey("Then the values are returned by the existing getters and context keys", func() {
+			So(GetRequestId(ctx), ShouldEqual, "request-123")
+			So(FlorenceIdentity(ctx), ShouldEqual, "florence-token")
+			So(IsFlorenceIdentityPresent(ctx), ShouldBeTrue)
+			So(Locale(ctx), ShouldEqual, "en")
+			So(CollectionID(ctx), ShouldEqual, "collection-1")
+			So(ctx.Value(LocaleContextKey), ShouldEqual, "en")
+			So(IsCallerPresent(ctx), ShouldBeFalse)
+		})
+
+		Convey("When values are set with the existing setters", func() {
+			ctx = SetCaller(ctx, "caller")
+			ctx = SetUser(ctx, "user@ons.gov.uk")
+			ctx = WithRequestId(ctx, "request-456")
+
+			Convey("Then the Info is updated", func() {
+				info := GetInfo(ctx)
+				So(info.Caller, ShouldEqual, "caller")
+				So(info.User, ShouldEqual, "user@ons.gov.uk")
+				So(info.RequestID, ShouldEqual, "request-456")
+				So(info.Locale, ShouldEqual, "en")
+				So(info.StartTime, ShouldEqual, start)
+				So(ctx.Value(CallerIdentityKey), ShouldEqual, "caller")
+			})
+		})
+
+		Convey("When a value is set under its individual context key", func() {
+			ctx = context.WithValue(ctx, LocaleContextKey, "cy")
+
+			Convey("Then it takes precedence over the Info", func() {
+				So(Locale(ctx), ShouldEqual, "cy")
+			})
+		})
+	})
+}
+
+func TestHandlerInfo(t *testing.T) {
+	Convey("Given a handler wrapped by the HandlerInfo middleware", t, func() {
+		var info Info
+		var requestIDHeader string
+		handler := HandlerInfo(InfoConfig{RequestIDSize: 10})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			info = GetInfo(req.Context())
+			requestIDHeader = req.Header.Get(RequestHeaderKey)
+		}))
+
+		Convey("When a request is made without a request id", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://cy.localhost/datasets", http.NoBody)
+			req.RemoteAddr = "10.1.2.3:4567"
+			req.Header.Set(FlorenceHeaderKey, "florence-token")
+			req.Header.Set(CollectionIDHeaderKey, "collection-1")
+			before := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			Convey("Then the Info is populated from the request", func() {
+				So(info.RequestID, ShouldHaveLength, 10)
+				So(requestIDHeader, ShouldEqual, info.RequestID)
+				So(info.FlorenceToken, ShouldEqual, "florence-token")
+				So(info.CollectionID, ShouldEqual, "collection-1")
+				So(info.Locale, ShouldEqual, LangCY)
+				So(info.ClientIP, ShouldEqual, "10.1.2.3")
+				So(info.StartTime, ShouldHappenOnOrAfter, before)
+			})
+		})
+
+		Convey("When a request is made with a request id and florence cookie", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/datasets", http.NoBody)
+			req.Header.Set(RequestHeaderKey, "request-123")
+			req.AddCookie(&http.Cookie{Name: FlorenceCookieKey, Value: "cookie-token"})
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			Convey("Then they are used", func() {
+				So(info.RequestID, ShouldEqual, "request-123")
+				So(info.FlorenceToken, ShouldEqual, "cookie-token")
+				So(info.Locale, ShouldEqual, LangEN)
+			})
+		})
+	})
+}
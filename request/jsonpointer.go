@@ -0,0 +1,59 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPointer is returned when a JSON Pointer is not valid according to RFC 6901
+var ErrInvalidPointer = errors.New("invalid JSON pointer")
+
+// JSONPointer is a JSON Pointer, according to RFC 6901, parsed into its unescaped reference tokens.
+// The empty pointer refers to the whole document.
+type JSONPointer []string
+
+// ParsePointer parses a JSON Pointer string, e.g. "/dimensions/0/name", unescaping '~1' to '/' and '~0' to '~'
+// in each reference token
+func ParsePointer(s string) (JSONPointer, error) {
+	if s == "" {
+		return JSONPointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: %q must be empty or start with '/'", ErrInvalidPointer, s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j == len(token)-1 || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: %q contains an invalid escape sequence", ErrInvalidPointer, s)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// String returns the JSON Pointer string, escaping '~' and '/' in each reference token
+func (p JSONPointer) String() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// IsPrefixOf returns true if p refers to a location that contains, or is the same as, the location of other
+func (p JSONPointer) IsPrefixOf(other JSONPointer) bool {
+	if len(p) > len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
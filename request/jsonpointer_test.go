@@ -0,0 +1,52 @@
+package request
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParsePointer(t *testing.T) {
+	Convey("The empty pointer refers to the whole document", t, func() {
+		p, err := ParsePointer("")
+		So(err, ShouldBeNil)
+		So(p, ShouldBeEmpty)
+		So(p.String(), ShouldEqual, "")
+	})
+
+	Convey("A pointer is split into its reference tokens", t, func() {
+		p, err := ParsePointer("/dimensions/0/name")
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, JSONPointer{"dimensions", "0", "name"})
+	})
+
+	Convey("Escaped characters are unescaped, and escaped again by String", t, func() {
+		p, err := ParsePointer("/a~1b/m~0n/~01")
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, JSONPointer{"a/b", "m~n", "~1"})
+		So(p.String(), ShouldEqual, "/a~1b/m~0n/~01")
+	})
+
+	Convey("Empty reference tokens are valid", t, func() {
+		p, err := ParsePointer("/")
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, JSONPointer{""})
+	})
+
+	Convey("Invalid pointers return ErrInvalidPointer", t, func() {
+		for _, s := range []string{"a/b", "/a~2", "/a~"} {
+			_, err := ParsePointer(s)
+			So(err, ShouldWrap, ErrInvalidPointer)
+		}
+	})
+}
+
+func TestJSONPointerIsPrefixOf(t *testing.T) {
+	Convey("IsPrefixOf compares whole reference tokens", t, func() {
+		So(JSONPointer{"a"}.IsPrefixOf(JSONPointer{"a", "b"}), ShouldBeTrue)
+		So(JSONPointer{"a", "b"}.IsPrefixOf(JSONPointer{"a", "b"}), ShouldBeTrue)
+		So(JSONPointer{}.IsPrefixOf(JSONPointer{"a"}), ShouldBeTrue)
+		So(JSONPointer{"a"}.IsPrefixOf(JSONPointer{"ab"}), ShouldBeFalse)
+		So(JSONPointer{"a", "b"}.IsPrefixOf(JSONPointer{"a"}), ShouldBeFalse)
+	})
+}
@@ -2,6 +2,9 @@ package request
 
 import (
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +16,10 @@ const (
 
 	LocaleCookieKey = "lang"
 	LocaleHeaderKey = "LocaleCode"
+	LocaleQueryKey  = "lang"
+
+	AcceptLanguageHeader  = "Accept-Language"
+	ContentLanguageHeader = "Content-Language"
 )
 
 var SupportedLanguages = [2]string{LangEN, LangCY}
@@ -56,3 +63,177 @@ func GetLangFromCookieOrDefault(c *http.Cookie) string {
 	}
 	return DefaultLang
 }
+
+// LocaleConfig is the configuration of locale negotiation
+type LocaleConfig struct {
+	// Supported are the BCP 47 language tags of the supported locales, e.g. "en", "cy" or "en-GB"
+	Supported []string
+	// Default is the locale used when no supported locale is requested. If empty, the first supported locale is used.
+	Default string
+}
+
+// DefaultLocaleConfig supports the SupportedLanguages, defaulting to DefaultLang
+var DefaultLocaleConfig = LocaleConfig{Supported: SupportedLanguages[:], Default: DefaultLang}
+
+// DefaultLocale returns the locale used when no supported locale is requested
+func (cfg LocaleConfig) DefaultLocale() string {
+	if cfg.Default != "" {
+		return cfg.Default
+	}
+	if len(cfg.Supported) > 0 {
+		return cfg.Supported[0]
+	}
+	return DefaultLang
+}
+
+// NegotiateLocale returns the supported locale that best matches the request. The locale is requested, in order of
+// precedence, by the 'lang' query parameter, the 'lang' cookie, the subdomain, e.g. "cy.ons.gov.uk", or the
+// Accept-Language header. Each requested locale falls back to less specific tags, e.g. "en-GB" to "en", if it is not
+// supported. If no supported locale is requested, the default locale is returned.
+func NegotiateLocale(r *http.Request, cfg LocaleConfig) string {
+	if lang := r.URL.Query().Get(LocaleQueryKey); lang != "" {
+		if locale, ok := MatchLocale(lang, cfg.Supported); ok {
+			return locale
+		}
+	}
+
+	if c, err := r.Cookie(LocaleCookieKey); err == nil && c.Value != "" {
+		if locale, ok := MatchLocale(c.Value, cfg.Supported); ok {
+			return locale
+		}
+	}
+
+	if subdomain, _, found := strings.Cut(r.Host, "."); found {
+		if locale, ok := MatchLocale(subdomain, cfg.Supported); ok {
+			return locale
+		}
+	}
+
+	if locale, ok := NegotiateAcceptLanguage(r.Header.Get(AcceptLanguageHeader), cfg.Supported); ok {
+		return locale
+	}
+	return cfg.DefaultLocale()
+}
+
+// LanguagePreference is a language range from an Accept-Language header, with its q-value
+type LanguagePreference struct {
+	Tag string
+	Q   float64
+}
+
+// ParseAcceptLanguage parses an Accept-Language header value, returning the language preferences in order of their
+// q-value, with ties in the order given. Ranges that are not valid, or have a q-value of zero, are omitted.
+func ParseAcceptLanguage(acceptLanguage string) []LanguagePreference {
+	prefs := []LanguagePreference{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := ParseQValue(part)
+		if q == 0 || (tag != "*" && !IsValidLanguageTag(tag)) {
+			continue
+		}
+		prefs = append(prefs, LanguagePreference{Tag: CanonicalLanguageTag(tag), Q: q})
+	}
+	slices.SortStableFunc(prefs, func(a, b LanguagePreference) int {
+		switch {
+		case a.Q > b.Q:
+			return -1
+		case a.Q < b.Q:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return prefs
+}
+
+// NegotiateAcceptLanguage returns the supported locale that best matches the Accept-Language header value, see
+// MatchLocale. A "*" range matches the first supported locale.
+func NegotiateAcceptLanguage(acceptLanguage string, supported []string) (string, bool) {
+	for _, pref := range ParseAcceptLanguage(acceptLanguage) {
+		if pref.Tag == "*" && len(supported) > 0 {
+			return supported[0], true
+		}
+		if locale, ok := MatchLocale(pref.Tag, supported); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// MatchLocale returns the supported locale matching the BCP 47 language tag, ignoring case and treating '_' as '-'.
+// If the tag is not supported, its subtags are removed from the end until it is, according to the lookup scheme of
+// RFC 4647, e.g. "zh-Hant-TW" falls back to "zh-Hant" and then "zh".
+func MatchLocale(tag string, supported []string) (string, bool) {
+	tag = strings.ReplaceAll(tag, "_", "-")
+	if !IsValidLanguageTag(tag) {
+		return "", false
+	}
+
+	for {
+		for _, locale := range supported {
+			if strings.EqualFold(locale, tag) {
+				return locale, true
+			}
+		}
+
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			return "", false
+		}
+		tag = tag[:i]
+		// a single character subtag, such as an extension or private use singleton, cannot end a tag
+		if j := strings.LastIndexByte(tag, '-'); j >= 0 && j == len(tag)-2 {
+			tag = tag[:j]
+		}
+	}
+}
+
+var languageTagRegexp = regexp.MustCompile(`^(?i:[a-z]{2,8}(-[a-z0-9]{1,8})*|[xi](-[a-z0-9]{1,8})+)$`)
+
+// IsValidLanguageTag returns true if the tag is well-formed according to the syntax of BCP 47, i.e. a primary
+// language subtag followed by any number of alphanumeric subtags of up to 8 characters, or a private use tag
+func IsValidLanguageTag(tag string) bool {
+	return languageTagRegexp.MatchString(tag)
+}
+
+// CanonicalLanguageTag returns the tag with the case conventions of BCP 47: lower case language, title case script,
+// e.g. "Hant", and upper case region, e.g. "GB". Subtags following a singleton, such as extensions, are lower case.
+func CanonicalLanguageTag(tag string) string {
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	for i := 1; i < len(subtags); i++ {
+		subtag := subtags[i]
+		if len(subtag) == 1 {
+			break
+		}
+		switch {
+		case len(subtag) == 2:
+			subtags[i] = strings.ToUpper(subtag)
+		case len(subtag) == 4 && !isDigit(subtag[0]):
+			subtags[i] = strings.ToUpper(subtag[:1]) + subtag[1:]
+		}
+	}
+	return strings.Join(subtags, "-")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ParseQValue parses a single element of a header such as Accept-Encoding or Accept-Language,
+// returning the lower-cased value and its q-value (1 if not specified, 0 if invalid)
+func ParseQValue(part string) (string, float64) {
+	params := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, param := range params[1:] {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || strings.ToLower(strings.TrimSpace(key)) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return name, 0
+		}
+		q = parsed
+	}
+	return name, q
+}
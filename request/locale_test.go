@@ -196,3 +196,151 @@ func TestGetLocaleCode(t *testing.T) {
 		})
 	})
 }
+
+func TestNegotiateLocale(t *testing.T) {
+	cfg := LocaleConfig{Supported: []string{"en", "cy", "en-GB", "zh-Hant"}, Default: "cy"}
+
+	newRequest := func(url string) *http.Request {
+		req, _ := http.NewRequest("GET", url, http.NoBody)
+		return req
+	}
+
+	Convey("Given a request with a supported 'lang' query parameter, cookie, subdomain and Accept-Language", t, func() {
+		req := newRequest("http://cy.localhost:21800/jobs?lang=zh-hant-tw")
+		req.AddCookie(&http.Cookie{Name: "lang", Value: "en-GB"})
+		req.Header.Set("Accept-Language", "en")
+
+		Convey("Then the query parameter takes precedence, falling back to a less specific tag", func() {
+			So(NegotiateLocale(req, cfg), ShouldEqual, "zh-Hant")
+		})
+	})
+
+	Convey("Given a request with an unsupported 'lang' query parameter and a supported cookie", t, func() {
+		req := newRequest("http://localhost:21800/jobs?lang=de")
+		req.AddCookie(&http.Cookie{Name: "lang", Value: "en_gb"})
+
+		Convey("Then the cookie is used", func() {
+			So(NegotiateLocale(req, cfg), ShouldEqual, "en-GB")
+		})
+	})
+
+	Convey("Given a request on a supported subdomain, with an Accept-Language header", t, func() {
+		req := newRequest("http://cy.localhost:21800/jobs")
+		req.Header.Set("Accept-Language", "en")
+
+		Convey("Then the subdomain is used", func() {
+			So(NegotiateLocale(req, cfg), ShouldEqual, "cy")
+		})
+	})
+
+	Convey("Given a request with only an Accept-Language header", t, func() {
+		req := newRequest("http://localhost:21800/jobs")
+		req.Header.Set("Accept-Language", "fr;q=0.9, en-US;q=0.8")
+
+		Convey("Then the best supported locale is used", func() {
+			So(NegotiateLocale(req, cfg), ShouldEqual, "en")
+		})
+	})
+
+	Convey("Given a request with no supported locale", t, func() {
+		req := newRequest("http://www.localhost:21800/jobs?lang=fr")
+		req.Header.Set("Accept-Language", "fr")
+
+		Convey("Then the default locale is used", func() {
+			So(NegotiateLocale(req, cfg), ShouldEqual, "cy")
+			So(NegotiateLocale(req, LocaleConfig{Supported: []string{"en-GB"}}), ShouldEqual, "en-GB")
+			So(NegotiateLocale(req, LocaleConfig{}), ShouldEqual, DefaultLang)
+		})
+	})
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	Convey("Language preferences are ordered by q-value, with ties in the order given", t, func() {
+		So(ParseAcceptLanguage("da, en-gb;q=0.8, EN;q=0.7, fr;q=0.8, *;q=0.1"), ShouldResemble, []LanguagePreference{
+			{Tag: "da", Q: 1},
+			{Tag: "en-GB", Q: 0.8},
+			{Tag: "fr", Q: 0.8},
+			{Tag: "en", Q: 0.7},
+			{Tag: "*", Q: 0.1},
+		})
+	})
+
+	Convey("Invalid ranges and ranges with a q-value of zero are omitted", t, func() {
+		So(ParseAcceptLanguage("en;q=0, 123, cy;q=2, , toolongtag"), ShouldBeEmpty)
+	})
+}
+
+func TestNegotiateAcceptLanguage(t *testing.T) {
+	supported := []string{"en", "cy"}
+
+	Convey("The most preferred supported locale is returned", t, func() {
+		locale, ok := NegotiateAcceptLanguage("fr, cy-GB;q=0.9, en;q=0.8", supported)
+		So(ok, ShouldBeTrue)
+		So(locale, ShouldEqual, "cy")
+	})
+
+	Convey("A wildcard matches the first supported locale", t, func() {
+		locale, ok := NegotiateAcceptLanguage("fr, *;q=0.5", supported)
+		So(ok, ShouldBeTrue)
+		So(locale, ShouldEqual, "en")
+	})
+
+	Convey("No locale is returned if none are supported", t, func() {
+		_, ok := NegotiateAcceptLanguage("fr, de", supported)
+		So(ok, ShouldBeFalse)
+		_, ok = NegotiateAcceptLanguage("", supported)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestMatchLocale(t *testing.T) {
+	supported := []string{"en", "zh-Hant", "sr-Latn-RS"}
+
+	Convey("Tags fall back to less specific tags until a supported locale matches", t, func() {
+		for tag, expected := range map[string]string{
+			"EN":                "en",
+			"en-GB":             "en",
+			"en_GB":             "en",
+			"zh-Hant-TW":        "zh-Hant",
+			"sr-latn-rs":        "sr-Latn-RS",
+			"en-GB-x-private":   "en",
+			"en-u-ca-gregory-x": "en",
+		} {
+			locale, ok := MatchLocale(tag, supported)
+			So(ok, ShouldBeTrue)
+			So(locale, ShouldEqual, expected)
+		}
+	})
+
+	Convey("Unsupported or invalid tags do not match", t, func() {
+		for _, tag := range []string{"zh", "zh-Hans", "sr", "", "e", "en--GB", "en GB"} {
+			_, ok := MatchLocale(tag, supported)
+			So(ok, ShouldBeFalse)
+		}
+	})
+}
+
+func TestCanonicalLanguageTag(t *testing.T) {
+	Convey("Tags are given the case conventions of BCP 47", t, func() {
+		So(CanonicalLanguageTag("EN-gb"), ShouldEqual, "en-GB")
+		So(CanonicalLanguageTag("zh-hant-tw"), ShouldEqual, "zh-Hant-TW")
+		So(CanonicalLanguageTag("es-419"), ShouldEqual, "es-419")
+		So(CanonicalLanguageTag("de-CH-1901"), ShouldEqual, "de-CH-1901")
+		So(CanonicalLanguageTag("en-X-Private-AB"), ShouldEqual, "en-x-private-ab")
+	})
+}
+
+func TestParseQValue(t *testing.T) {
+	Convey("The value is lower-cased and its q-value parsed", t, func() {
+		name, q := ParseQValue(" EN-GB ; q=0.5 ")
+		So(name, ShouldEqual, "en-gb")
+		So(q, ShouldEqual, 0.5)
+	})
+
+	Convey("The q-value is 1 if not specified and 0 if invalid", t, func() {
+		_, q := ParseQValue("gzip")
+		So(q, ShouldEqual, 1)
+		_, q = ParseQValue("gzip;q=x")
+		So(q, ShouldEqual, 0)
+	})
+}
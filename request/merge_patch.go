@@ -0,0 +1,168 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+)
+
+// Content types of patch request bodies
+const (
+	ContentTypeJSONPatch  = "application/json-patch+json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// ErrUnsupportedPatchContentType is returned when a patch request body is neither a JSON Patch nor a JSON Merge Patch
+var ErrUnsupportedPatchContentType = errors.New("unsupported patch content type")
+
+// MergePatch models an HTTP merge patch request, according to RFC 7396. Members with a null value are removed from
+// the target, object members are merged recursively, and any other member replaces the target's value.
+type MergePatch map[string]interface{}
+
+// GetMergePatch gets the merge patch from the request body.
+// An error will be returned if request body cannot be read, or is not a JSON object
+func GetMergePatch(requestBody io.ReadCloser) (MergePatch, error) {
+	bytes, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read and get merge patch request body")
+	}
+
+	if len(bytes) == 0 {
+		return nil, fmt.Errorf("empty request body given")
+	}
+
+	var patch MergePatch
+	if err := json.Unmarshal(bytes, &patch); err != nil || patch == nil {
+		return nil, fmt.Errorf("failed to unmarshal merge patch request body, which must be a JSON object")
+	}
+	return patch, nil
+}
+
+// GetPatchesByContentType gets the patches from the request body according to its Content-Type, so that JSON Patch
+// and JSON Merge Patch requests can be handled in the same way. A merge patch is converted to the equivalent patches
+// for the current document, see MergePatch.Patches, and all patches are validated against the supported ops.
+// A request with no Content-Type, or 'application/json', is treated as a JSON Patch.
+func GetPatchesByContentType(req *http.Request, doc interface{}, supportedOps []PatchOp) ([]Patch, error) {
+	mediaType := ContentTypeJSONPatch
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return []Patch{}, fmt.Errorf("%w: %s", ErrUnsupportedPatchContentType, contentType)
+		}
+	}
+
+	switch mediaType {
+	case ContentTypeJSONPatch, "application/json":
+		return GetPatches(req.Body, supportedOps)
+	case ContentTypeMergePatch:
+		mergePatch, err := GetMergePatch(req.Body)
+		if err != nil {
+			return []Patch{}, err
+		}
+		patches, err := mergePatch.Patches(doc)
+		if err != nil {
+			return []Patch{}, err
+		}
+		for _, patch := range patches {
+			if err := patch.Validate(supportedOps); err != nil {
+				return []Patch{}, err
+			}
+		}
+		return patches, nil
+	default:
+		return []Patch{}, fmt.Errorf("%w: %s", ErrUnsupportedPatchContentType, mediaType)
+	}
+}
+
+// ApplyMergePatch applies the merge patch to the document, which must be a non-nil pointer, in the same way as
+// ApplyPatches. The document is left unchanged if the patched document cannot be unmarshalled into it.
+func ApplyMergePatch(doc interface{}, patch MergePatch) error {
+	return patchDocument(doc, func(b []byte) ([]byte, error) {
+		return ApplyMergePatchJSON(b, patch)
+	})
+}
+
+// ApplyMergePatchJSON applies the merge patch to a JSON document, returning the patched document
+func ApplyMergePatchJSON(doc []byte, patch MergePatch) ([]byte, error) {
+	node, err := decodeJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+	return json.Marshal(mergeValue(node, map[string]interface{}(patch)))
+}
+
+// mergeValue merges the patch into the target according to RFC 7396. The patch is not modified.
+func mergeValue(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergeValue(t[k], v)
+		}
+	}
+	return t
+}
+
+// Patches converts the merge patch to the equivalent JSON Patch operations for the document, so that they can be
+// authorised and validated in the same way as a JSON Patch. The conversion depends on the document, as a merge
+// patch member may add, replace or remove a value, or do nothing, depending on the current value. Members are
+// converted in order of their keys, and nested objects are only converted member by member where the document
+// already has an object at that path.
+func (m MergePatch) Patches(doc interface{}) ([]Patch, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %w", err)
+	}
+	node, err := decodeJSON(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+
+	target, _ := node.(map[string]interface{})
+	patches := []Patch{}
+	mergePatches(&patches, JSONPointer{}, target, m)
+	return patches, nil
+}
+
+func mergePatches(patches *[]Patch, path JSONPointer, target, patch map[string]interface{}) {
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		memberPath := append(slices.Clip(path), k)
+		current, exists := target[k]
+		value := patch[k]
+
+		switch {
+		case value == nil:
+			if exists {
+				*patches = append(*patches, Patch{Op: OpRemove.String(), Path: memberPath.String()})
+			}
+		case !exists:
+			*patches = append(*patches, Patch{Op: OpAdd.String(), Path: memberPath.String(), Value: mergeValue(nil, value)})
+		default:
+			currentObj, isObj := current.(map[string]interface{})
+			valueObj, isPatchObj := value.(map[string]interface{})
+			if isObj && isPatchObj {
+				mergePatches(patches, memberPath, currentObj, valueObj)
+			} else {
+				*patches = append(*patches, Patch{Op: OpReplace.String(), Path: memberPath.String(), Value: mergeValue(nil, value)})
+			}
+		}
+	}
+}
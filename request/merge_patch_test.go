@@ -0,0 +1,164 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetMergePatch(t *testing.T) {
+	Convey("A merge patch request body is unmarshalled", t, func() {
+		patch, err := GetMergePatch(io.NopCloser(strings.NewReader(`{"title":"new","release":null}`)))
+		So(err, ShouldBeNil)
+		So(patch, ShouldResemble, MergePatch{"title": "new", "release": nil})
+	})
+
+	Convey("An empty request body returns an error", t, func() {
+		_, err := GetMergePatch(io.NopCloser(strings.NewReader("")))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("A request body that is not a JSON object returns an error", t, func() {
+		for _, body := range []string{`[1]`, `null`, `"title"`, `{`} {
+			_, err := GetMergePatch(io.NopCloser(strings.NewReader(body)))
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestApplyMergePatchJSON(t *testing.T) {
+	// examples from RFC 7396 Appendix A, where the patch is an object
+	for _, tc := range []struct{ doc, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `{"a":"b"}`, `{"a":"b"}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		Convey("Merge patch "+tc.patch+" applied to "+tc.doc, t, func() {
+			patch, err := GetMergePatch(io.NopCloser(strings.NewReader(tc.patch)))
+			So(err, ShouldBeNil)
+
+			patched, err := ApplyMergePatchJSON([]byte(tc.doc), patch)
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, tc.expected)
+
+			Convey("Is equivalent to the converted patches", func() {
+				patches, err := patch.Patches(mustDecode(tc.doc))
+				So(err, ShouldBeNil)
+
+				if strings.HasPrefix(tc.doc, "{") {
+					converted, err := ApplyPatchesJSON([]byte(tc.doc), patches)
+					So(err, ShouldBeNil)
+					So(string(converted), ShouldEqual, tc.expected)
+				}
+			})
+		})
+	}
+}
+
+func mustDecode(s string) interface{} {
+	v, err := decodeJSON([]byte(s))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	Convey("Given a struct with JSON tags", t, func() {
+		dataset := &testDataset{ID: "cpih", Title: "Consumer prices", Version: 1}
+
+		Convey("Then a merge patch is applied to its JSON representation", func() {
+			err := ApplyMergePatch(dataset, MergePatch{"title": nil, "version": 2})
+			So(err, ShouldBeNil)
+			So(dataset, ShouldResemble, &testDataset{ID: "cpih", Version: 2})
+		})
+
+		Convey("Then the struct is unchanged if the patched document cannot be unmarshalled into it", func() {
+			err := ApplyMergePatch(dataset, MergePatch{"title": "changed", "version": "two"})
+			So(err, ShouldNotBeNil)
+			So(dataset, ShouldResemble, &testDataset{ID: "cpih", Title: "Consumer prices", Version: 1})
+		})
+	})
+}
+
+func TestMergePatchPatches(t *testing.T) {
+	Convey("Given a document", t, func() {
+		dataset := testDataset{
+			ID:         "cpih",
+			Title:      "Consumer prices",
+			Dimensions: []testDimension{{Name: "time"}},
+		}
+
+		Convey("When a merge patch is converted to patches", func() {
+			patch := MergePatch{
+				"title":      nil,
+				"release":    nil,
+				"version":    2,
+				"a/b":        map[string]interface{}{"c": nil, "d": "e"},
+				"dimensions": []interface{}{},
+			}
+			patches, err := patch.Patches(dataset)
+
+			Convey("Then each member is converted to the equivalent op, in order of the keys", func() {
+				So(err, ShouldBeNil)
+				So(patches, ShouldResemble, []Patch{
+					{Op: "add", Path: "/a~1b", Value: map[string]interface{}{"d": "e"}},
+					{Op: "replace", Path: "/dimensions", Value: []interface{}{}},
+					{Op: "remove", Path: "/title"},
+					{Op: "replace", Path: "/version", Value: 2},
+				})
+			})
+		})
+	})
+}
+
+func TestGetPatchesByContentType(t *testing.T) {
+	dataset := testDataset{ID: "cpih", Title: "Consumer prices", Version: 1}
+	allSupportedOps := []PatchOp{OpAdd, OpRemove, OpReplace, OpMove, OpCopy, OpTest}
+
+	newRequest := func(contentType, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPatch, "/datasets/cpih", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	Convey("A JSON Patch request body is read with GetPatches", t, func() {
+		for _, contentType := range []string{"", "application/json", "application/json-patch+json; charset=utf-8"} {
+			patches, err := GetPatchesByContentType(newRequest(contentType, `[{"op":"replace","path":"/version","value":2}]`), dataset, allSupportedOps)
+			So(err, ShouldBeNil)
+			So(patches, ShouldResemble, []Patch{{Op: "replace", Path: "/version", Value: float64(2)}})
+		}
+	})
+
+	Convey("A JSON Merge Patch request body is converted to patches", t, func() {
+		patches, err := GetPatchesByContentType(newRequest(ContentTypeMergePatch, `{"version":2}`), dataset, allSupportedOps)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{{Op: "replace", Path: "/version", Value: float64(2)}})
+	})
+
+	Convey("A JSON Merge Patch that converts to an unsupported op returns an error", t, func() {
+		_, err := GetPatchesByContentType(newRequest(ContentTypeMergePatch, `{"title":null}`), dataset, []PatchOp{OpReplace})
+		So(err, ShouldResemble, ErrUnsupportedOp("remove", []PatchOp{OpReplace}))
+	})
+
+	Convey("An unsupported content type returns ErrUnsupportedPatchContentType", t, func() {
+		_, err := GetPatchesByContentType(newRequest("text/plain", `{}`), dataset, allSupportedOps)
+		So(errors.Is(err, ErrUnsupportedPatchContentType), ShouldBeTrue)
+	})
+}
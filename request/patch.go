@@ -59,19 +59,68 @@ type Patch struct {
 	Op    string      `json:"op"`
 	Path  string      `json:"path"`
 	From  string      `json:"from"`
-	Value interface{} `json:"value"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// JSONNull is the Value of a patch that sets a JSON null value. Validate reports a nil Value as missing, so patches
+// that set null, such as those returned by Diff, have a JSONNull Value instead.
+type JSONNull struct{}
+
+// MarshalJSON marshals the value as a JSON null
+func (JSONNull) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON unmarshals the patch from JSON. An explicit null value is unmarshalled as a JSONNull Value, so that it
+// is not reported as missing, and a missing value is unmarshalled as a nil Value.
+func (p *Patch) UnmarshalJSON(b []byte) error {
+	type patch Patch
+	var members struct {
+		patch
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+
+	*p = Patch(members.patch)
+	switch {
+	case members.Value == nil:
+		p.Value = nil
+	case string(members.Value) == "null":
+		p.Value = JSONNull{}
+	default:
+		return json.Unmarshal(members.Value, &p.Value)
+	}
+	return nil
 }
 
 // GetPatches gets the patches from the request body and returns it in the form of []Patch.
 // An error will be returned if request body cannot be read, unmarshalling the requets body is unsuccessful,
-// no patches are provided in the request or any of the provided patches are invalid
+// no patches are provided in the request or any of the provided patches are invalid. A patch with an explicit null
+// value has a JSONNull Value.
 func GetPatches(requestBody io.ReadCloser, supportedOps []PatchOp) ([]Patch, error) {
-	patches := []Patch{}
-
 	if len(supportedOps) < 1 {
 		return []Patch{}, fmt.Errorf("empty list of support patch operations given")
 	}
 
+	patches, err := readPatches(requestBody)
+	if err != nil {
+		return []Patch{}, err
+	}
+
+	for _, patch := range patches {
+		if err := patch.Validate(supportedOps); err != nil {
+			return []Patch{}, err
+		}
+	}
+	return patches, nil
+}
+
+// readPatches reads and unmarshals the patches from the request body, without validating them
+func readPatches(requestBody io.ReadCloser) ([]Patch, error) {
+	patches := []Patch{}
+
 	bytes, err := io.ReadAll(requestBody)
 	if err != nil {
 		return []Patch{}, fmt.Errorf("failed to read and get patch request body")
@@ -89,12 +138,6 @@ func GetPatches(requestBody io.ReadCloser, supportedOps []PatchOp) ([]Patch, err
 	if len(patches) < 1 {
 		return []Patch{}, fmt.Errorf("no patches given in request body")
 	}
-
-	for _, patch := range patches {
-		if err := patch.Validate(supportedOps); err != nil {
-			return []Patch{}, err
-		}
-	}
 	return patches, nil
 }
 
@@ -0,0 +1,605 @@
+package request
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Errors returned when applying patches
+var (
+	ErrInvalidDocument = errors.New("document must be a non-nil pointer")
+	ErrPathNotFound    = errors.New("path not found")
+	ErrInvalidIndex    = errors.New("invalid array index")
+	ErrMoveIntoChild   = errors.New("cannot move a value into one of its children")
+)
+
+var allOps = []PatchOp{OpAdd, OpRemove, OpReplace, OpMove, OpCopy, OpTest}
+
+// PatchError is returned by ApplyPatches when a patch cannot be applied, identifying the patch by its index
+type PatchError struct {
+	Index int
+	Patch Patch
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("failed to apply patch %d (%s %s): %v", e.Index, e.Patch.Op, e.Patch.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// TestFailedError is returned, wrapped in a PatchError, when the value at the path of a 'test' operation does not
+// equal the expected value
+type TestFailedError struct {
+	Path     string
+	Expected interface{}
+	Actual   interface{}
+}
+
+func (e *TestFailedError) Error() string {
+	return fmt.Sprintf("test failed: value at path '%s' is %v, expected %v", e.Path, e.Actual, e.Expected)
+}
+
+// ApplyPatches applies the patches to the document according to RFC 6902. The document must be a non-nil pointer to
+// a value that can be marshalled to and from JSON, e.g. a struct with JSON tags or a map[string]interface{}, and is
+// patched by its JSON representation. Patches are applied atomically: if any patch fails, including a 'test', the
+// document is left unchanged and a *PatchError is returned. Fields of a struct that are not marshalled to JSON, such as
+// unexported fields and fields tagged `json:"-"`, are kept.
+func ApplyPatches(doc interface{}, patches []Patch) error {
+	return patchDocument(doc, func(b []byte) ([]byte, error) {
+		return ApplyPatchesJSON(b, patches)
+	})
+}
+
+// patchDocument patches the JSON representation of the document, which must be a non-nil pointer, replacing the
+// document's value only if patching succeeds
+func patchDocument(doc interface{}, patch func([]byte) ([]byte, error)) error {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrInvalidDocument
+	}
+
+	b, err := marshalDocument(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+	patched, err := patch(b)
+	if err != nil {
+		return err
+	}
+
+	// unmarshal into a copy whose JSON fields are reset, so that removed fields are not left set, fields that are not
+	// marshalled to JSON are kept, and the document is unchanged on error
+	result := reflect.New(v.Elem().Type())
+	if v.Elem().Kind() == reflect.Struct {
+		result.Elem().Set(v.Elem())
+		resetJSONFields(result.Elem())
+	}
+	if err := json.Unmarshal(patched, result.Interface()); err != nil {
+		return fmt.Errorf("failed to unmarshal patched document: %w", err)
+	}
+	keepNil(result.Elem(), v.Elem())
+	v.Elem().Set(result.Elem())
+	return nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// marshalDocument marshals the document to JSON, including the fields of structs that are omitted when empty and
+// marshalling nil slices and maps as empty, so that patches can replace and add to every field of the document
+func marshalDocument(v reflect.Value) ([]byte, error) {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fillDocument(doc, v))
+}
+
+// marshalsItself returns whether the value has its own JSON representation, which must not be filled
+func marshalsItself(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	p := reflect.PointerTo(t)
+	return v.CanAddr() && (p.Implements(jsonMarshalerType) || p.Implements(textMarshalerType))
+}
+
+// fillDocument adds the struct fields of the value that were omitted from its decoded JSON, and replaces the nulls of
+// nil slices and maps with empty ones
+func fillDocument(doc interface{}, v reflect.Value) interface{} {
+	if !v.IsValid() || marshalsItself(v) {
+		return doc
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return fillDocument(doc, v.Elem())
+		}
+	case reflect.Struct:
+		if obj, ok := doc.(map[string]interface{}); ok {
+			fillStruct(obj, v, make(map[string]bool))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return map[string]interface{}{}
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return doc
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			obj[key] = fillDocument(obj[key], iter.Value())
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// byte slices are marshalled as base64 strings
+			return doc
+		}
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		fallthrough
+	case reflect.Array:
+		if arr, ok := doc.([]interface{}); ok && len(arr) == v.Len() {
+			for i := range arr {
+				arr[i] = fillDocument(arr[i], v.Index(i))
+			}
+		}
+	}
+	return doc
+}
+
+// fillStruct fills the fields of the struct in its decoded JSON object. Fields are claimed by name so that, as when
+// marshalling, the fields of embedded structs do not replace the fields of the structs that embed them.
+func fillStruct(obj map[string]interface{}, v reflect.Value, claimed map[string]bool) {
+	t := v.Type()
+	var embedded []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, v.Field(i))
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if claimed[name] {
+			continue
+		}
+		claimed[name] = true
+
+		value, ok := obj[name]
+		if !ok {
+			value = emptyValue(v.Field(i), slices.Contains(strings.Split(opts, ","), "string"))
+		}
+		obj[name] = fillDocument(value, v.Field(i))
+	}
+
+	for _, e := range embedded {
+		if e.Kind() == reflect.Pointer {
+			if e.IsNil() {
+				continue
+			}
+			e = e.Elem()
+		}
+		fillStruct(obj, e, claimed)
+	}
+}
+
+// emptyValue returns the JSON value of a field that was omitted because it is empty
+func emptyValue(v reflect.Value, quoted bool) interface{} {
+	if marshalsItself(v) {
+		if value, err := normalise(v.Interface()); err == nil {
+			return value
+		}
+		return nil
+	}
+
+	var value interface{}
+	switch v.Kind() {
+	case reflect.Bool:
+		value = false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		value = json.Number("0")
+	case reflect.String:
+		value = ""
+	case reflect.Struct, reflect.Map:
+		return map[string]interface{}{}
+	case reflect.Slice, reflect.Array:
+		return []interface{}{}
+	default:
+		return nil
+	}
+	if quoted {
+		// the field is marshalled as a JSON string containing its JSON value
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+	return value
+}
+
+// keepNil resets the slices and maps of the patched value that are empty to nil if they were nil before patching, so
+// that fields which were filled as empty but not patched are unchanged
+func keepNil(patched, original reflect.Value) {
+	if patched.Type() != original.Type() || marshalsItself(patched) {
+		return
+	}
+
+	switch patched.Kind() {
+	case reflect.Pointer:
+		if !patched.IsNil() && !original.IsNil() {
+			keepNil(patched.Elem(), original.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < patched.NumField(); i++ {
+			keepNil(patched.Field(i), original.Field(i))
+		}
+	case reflect.Map:
+		if original.IsNil() && patched.Len() == 0 && patched.CanSet() {
+			patched.SetZero()
+		}
+	case reflect.Slice:
+		if original.IsNil() && patched.Len() == 0 && patched.CanSet() {
+			patched.SetZero()
+			return
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < min(patched.Len(), original.Len()); i++ {
+			keepNil(patched.Index(i), original.Index(i))
+		}
+	}
+}
+
+// resetJSONFields sets the fields of the struct that are marshalled to JSON to their zero values
+func resetJSONFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			// the fields of embedded structs are promoted to the fields of the document
+			resetJSONFields(v.Field(i))
+			continue
+		}
+		if field.IsExported() {
+			v.Field(i).SetZero()
+		}
+	}
+}
+
+// ApplyPatchesJSON applies the patches to a JSON document according to RFC 6902, returning the patched document.
+// If any patch fails, a *PatchError is returned.
+func ApplyPatchesJSON(doc []byte, patches []Patch) ([]byte, error) {
+	node, err := decodeJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+
+	for i, patch := range patches {
+		if node, err = applyPatch(node, patch); err != nil {
+			return nil, &PatchError{Index: i, Patch: patch, Err: err}
+		}
+	}
+	return json.Marshal(node)
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// normalise converts a value to its generic JSON representation, so that it can be compared and patched into a
+// decoded document
+func normalise(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return decodeJSON(b)
+}
+
+func applyPatch(node interface{}, patch Patch) (interface{}, error) {
+	path, err := ParsePointer(patch.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patch.Op {
+	case OpAdd.String():
+		value, err := normalise(patch.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(node, path, value)
+	case OpRemove.String():
+		node, _, err = remove(node, path)
+		return node, err
+	case OpReplace.String():
+		value, err := normalise(patch.Value)
+		if err != nil {
+			return nil, err
+		}
+		return replace(node, path, value)
+	case OpMove.String():
+		from, err := ParsePointer(patch.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) < len(path) && from.IsPrefixOf(path) {
+			return nil, ErrMoveIntoChild
+		}
+		node, value, err := remove(node, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(node, path, value)
+	case OpCopy.String():
+		from, err := ParsePointer(patch.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(node, from)
+		if err != nil {
+			return nil, err
+		}
+		// normalising copies the value, so that the copies are independent
+		if value, err = normalise(value); err != nil {
+			return nil, err
+		}
+		return add(node, path, value)
+	case OpTest.String():
+		expected, err := normalise(patch.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(node, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, expected) {
+			return nil, &TestFailedError{Path: patch.Path, Expected: expected, Actual: actual}
+		}
+		return node, nil
+	default:
+		return nil, ErrUnsupportedOp(patch.Op, allOps)
+	}
+}
+
+// get returns the value at the path
+func get(node interface{}, path JSONPointer) (interface{}, error) {
+	for i, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path[:i+1])
+			}
+			node = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path[:i+1])
+		}
+	}
+	return node, nil
+}
+
+// update calls fn with the container at the parent of the path and the path's last token, returning the node with
+// the container replaced by the one returned by fn
+func update(node interface{}, path JSONPointer, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: /%s", ErrPathNotFound, path[0])
+		}
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = updated
+		return n, nil
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(n[idx], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: /%s", ErrPathNotFound, path[0])
+	}
+}
+
+func add(node interface{}, path JSONPointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(node, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			if token == "-" {
+				return append(c, value), nil
+			}
+			idx, err := arrayIndex(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[idx+1:], c[idx:])
+			c[idx] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	})
+}
+
+func remove(node interface{}, path JSONPointer) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrPathNotFound)
+	}
+
+	var removed interface{}
+	node, err := update(node, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			value, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[idx]
+			return append(c[:idx], c[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	})
+	return node, removed, err
+}
+
+func replace(node interface{}, path JSONPointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(node, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			c[idx] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	})
+}
+
+// arrayIndex parses an array index token, which must be no greater than maxIndex
+func arrayIndex(token string, maxIndex int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidIndex, token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > maxIndex {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidIndex, token)
+	}
+	return idx, nil
+}
+
+// jsonEqual compares two generic JSON values, treating numbers as equal if they have the same value
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if av == bv {
+			return true
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
@@ -0,0 +1,315 @@
+package request
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testDimension struct {
+	Name    string   `json:"name"`
+	Options []string `json:"options,omitempty"`
+}
+
+type testDataset struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title,omitempty"`
+	Version    int             `json:"version"`
+	Dimensions []testDimension `json:"dimensions,omitempty"`
+}
+
+type testEdition struct {
+	ID    string            `json:"id"`
+	State string            `json:"state,omitempty"`
+	Tags  []string          `json:"tags"`
+	Links map[string]string `json:"links,omitempty"`
+	Alias *string           `json:"alias,omitempty"`
+}
+
+type testAudit struct {
+	ETag string `json:"-"`
+}
+
+type testInternalDataset struct {
+	testAudit
+	testDataset
+	Internal string `json:"-"`
+	secret   string
+}
+
+func TestApplyPatchesJSON(t *testing.T) {
+	Convey("Given a JSON document", t, func() {
+		doc := []byte(`{"foo":"bar","baz":[1,2,3],"a/b":{"m~n":true}}`)
+
+		Convey("Then an add operation adds an object member", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{{Op: "add", Path: "/qux", Value: "quux"}})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `{"a/b":{"m~n":true},"baz":[1,2,3],"foo":"bar","qux":"quux"}`)
+		})
+
+		Convey("Then an add operation inserts into an array, or appends with '-'", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{
+				{Op: "add", Path: "/baz/1", Value: 9},
+				{Op: "add", Path: "/baz/-", Value: 10},
+				{Op: "add", Path: "/baz/5", Value: 11},
+			})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldContainSubstring, `"baz":[1,9,2,3,10,11]`)
+		})
+
+		Convey("Then a remove operation removes object members and array elements", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{{Op: "remove", Path: "/foo"}, {Op: "remove", Path: "/baz/0"}})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `{"a/b":{"m~n":true},"baz":[2,3]}`)
+		})
+
+		Convey("Then a replace operation replaces an existing value", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{{Op: "replace", Path: "/a~1b/m~0n", Value: false}})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldContainSubstring, `"a/b":{"m~n":false}`)
+		})
+
+		Convey("Then a replace operation with an empty path replaces the whole document", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{{Op: "replace", Path: "", Value: []int{1}}})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `[1]`)
+		})
+
+		Convey("Then a move operation moves a value", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{{Op: "move", From: "/baz/0", Path: "/baz/-"}, {Op: "move", From: "/foo", Path: "/bar"}})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `{"a/b":{"m~n":true},"bar":"bar","baz":[2,3,1]}`)
+		})
+
+		Convey("Then a copy operation copies a value independently of the original", func() {
+			patched, err := ApplyPatchesJSON(doc, []Patch{
+				{Op: "copy", From: "/a~1b", Path: "/c"},
+				{Op: "replace", Path: "/c/m~0n", Value: false},
+			})
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `{"a/b":{"m~n":true},"baz":[1,2,3],"c":{"m~n":false},"foo":"bar"}`)
+		})
+
+		Convey("Then a test operation compares values by their JSON representation", func() {
+			_, err := ApplyPatchesJSON(doc, []Patch{
+				{Op: "test", Path: "/baz", Value: []float64{1, 2.0, 3}},
+				{Op: "test", Path: "/a~1b", Value: map[string]bool{"m~n": true}},
+			})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then a failed test operation returns a TestFailedError in a PatchError", func() {
+			_, err := ApplyPatchesJSON(doc, []Patch{
+				{Op: "add", Path: "/qux", Value: 1},
+				{Op: "test", Path: "/foo", Value: "baz"},
+			})
+
+			var patchErr *PatchError
+			So(errors.As(err, &patchErr), ShouldBeTrue)
+			So(patchErr.Index, ShouldEqual, 1)
+
+			var testErr *TestFailedError
+			So(errors.As(err, &testErr), ShouldBeTrue)
+			So(testErr.Path, ShouldEqual, "/foo")
+			So(testErr.Expected, ShouldEqual, "baz")
+			So(testErr.Actual, ShouldEqual, "bar")
+		})
+
+		Convey("Then invalid patches return an error", func() {
+			for _, tc := range []struct {
+				patch Patch
+				err   error
+			}{
+				{Patch{Op: "add", Path: "/missing/child", Value: 1}, ErrPathNotFound},
+				{Patch{Op: "add", Path: "/baz/4", Value: 1}, ErrInvalidIndex},
+				{Patch{Op: "add", Path: "/baz/01", Value: 1}, ErrInvalidIndex},
+				{Patch{Op: "remove", Path: "/missing"}, ErrPathNotFound},
+				{Patch{Op: "remove", Path: "/baz/-"}, ErrInvalidIndex},
+				{Patch{Op: "replace", Path: "/missing", Value: 1}, ErrPathNotFound},
+				{Patch{Op: "replace", Path: "/baz/3", Value: 1}, ErrInvalidIndex},
+				{Patch{Op: "test", Path: "/foo/bar", Value: 1}, ErrPathNotFound},
+				{Patch{Op: "move", From: "/a~1b", Path: "/a~1b/child"}, ErrMoveIntoChild},
+				{Patch{Op: "copy", From: "/missing", Path: "/foo"}, ErrPathNotFound},
+				{Patch{Op: "add", Path: "foo", Value: 1}, ErrInvalidPointer},
+			} {
+				_, err := ApplyPatchesJSON(doc, []Patch{tc.patch})
+				So(err, ShouldWrap, tc.err)
+			}
+
+			_, err := ApplyPatchesJSON(doc, []Patch{{Op: "merge", Path: "/foo"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "patch operation 'merge' not supported")
+		})
+	})
+}
+
+func TestApplyPatches(t *testing.T) {
+	Convey("Given a struct with fields that are not marshalled to JSON", t, func() {
+		dataset := &testInternalDataset{
+			testAudit:   testAudit{ETag: "etag"},
+			testDataset: testDataset{ID: "cpih", Title: "a", Version: 1},
+			Internal:    "keep-me",
+			secret:      "shh",
+		}
+
+		Convey("When patches are applied", func() {
+			err := ApplyPatches(dataset, []Patch{
+				{Op: "replace", Path: "/title", Value: "b"},
+				{Op: "remove", Path: "/version"},
+			})
+
+			Convey("Then the JSON fields are patched and the other fields are kept", func() {
+				So(err, ShouldBeNil)
+				So(dataset, ShouldResemble, &testInternalDataset{
+					testAudit:   testAudit{ETag: "etag"},
+					testDataset: testDataset{ID: "cpih", Title: "b"},
+					Internal:    "keep-me",
+					secret:      "shh",
+				})
+			})
+		})
+
+		Convey("When a merge patch is applied", func() {
+			err := ApplyMergePatch(dataset, MergePatch{"title": nil})
+
+			Convey("Then the other fields are kept", func() {
+				So(err, ShouldBeNil)
+				So(dataset.Title, ShouldBeEmpty)
+				So(dataset.Internal, ShouldEqual, "keep-me")
+				So(dataset.secret, ShouldEqual, "shh")
+				So(dataset.ETag, ShouldEqual, "etag")
+			})
+		})
+	})
+
+	Convey("Given a struct with JSON tags", t, func() {
+		dataset := &testDataset{
+			ID:      "cpih",
+			Title:   "Consumer prices",
+			Version: 1,
+			Dimensions: []testDimension{
+				{Name: "time", Options: []string{"2020"}},
+			},
+		}
+
+		Convey("When valid patches are applied", func() {
+			err := ApplyPatches(dataset, []Patch{
+				{Op: "test", Path: "/version", Value: 1},
+				{Op: "replace", Path: "/version", Value: 2},
+				{Op: "remove", Path: "/title"},
+				{Op: "add", Path: "/dimensions/0/options/-", Value: "2021"},
+				{Op: "add", Path: "/dimensions/-", Value: testDimension{Name: "geography"}},
+			})
+
+			Convey("Then the struct is patched by its JSON representation", func() {
+				So(err, ShouldBeNil)
+				So(dataset, ShouldResemble, &testDataset{
+					ID:      "cpih",
+					Version: 2,
+					Dimensions: []testDimension{
+						{Name: "time", Options: []string{"2020", "2021"}},
+						{Name: "geography"},
+					},
+				})
+			})
+		})
+
+		Convey("When any patch fails", func() {
+			err := ApplyPatches(dataset, []Patch{
+				{Op: "replace", Path: "/title", Value: "changed"},
+				{Op: "test", Path: "/version", Value: 2},
+			})
+
+			Convey("Then the struct is unchanged", func() {
+				var testErr *TestFailedError
+				So(errors.As(err, &testErr), ShouldBeTrue)
+				So(dataset.Title, ShouldEqual, "Consumer prices")
+			})
+		})
+
+		Convey("When the patched document cannot be unmarshalled into the struct", func() {
+			err := ApplyPatches(dataset, []Patch{
+				{Op: "replace", Path: "/title", Value: "changed"},
+				{Op: "replace", Path: "/version", Value: "two"},
+			})
+
+			Convey("Then an error is returned and the struct is unchanged", func() {
+				So(err, ShouldNotBeNil)
+				So(dataset.Title, ShouldEqual, "Consumer prices")
+				So(dataset.Version, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a struct with empty fields that are omitted from its JSON, and nil slices and maps", t, func() {
+		edition := &testEdition{ID: "2021"}
+		dataset := &testDataset{ID: "cpih", Dimensions: []testDimension{{Name: "time"}}}
+
+		Convey("When patches replace and add to the empty fields", func() {
+			err := ApplyPatches(edition, []Patch{
+				{Op: "test", Path: "/state", Value: ""},
+				{Op: "replace", Path: "/state", Value: "published"},
+				{Op: "add", Path: "/tags/-", Value: "latest"},
+				{Op: "add", Path: "/links/self", Value: "/editions/2021"},
+			})
+
+			Convey("Then the fields are patched", func() {
+				So(err, ShouldBeNil)
+				So(edition, ShouldResemble, &testEdition{
+					ID:    "2021",
+					State: "published",
+					Tags:  []string{"latest"},
+					Links: map[string]string{"self": "/editions/2021"},
+				})
+			})
+		})
+
+		Convey("When patches add to nil slices in nested structs", func() {
+			err := ApplyPatches(dataset, []Patch{
+				{Op: "replace", Path: "/title", Value: "Consumer prices"},
+				{Op: "add", Path: "/dimensions/0/options/-", Value: "2020"},
+			})
+
+			Convey("Then the nested fields are patched", func() {
+				So(err, ShouldBeNil)
+				So(dataset, ShouldResemble, &testDataset{
+					ID:         "cpih",
+					Title:      "Consumer prices",
+					Dimensions: []testDimension{{Name: "time", Options: []string{"2020"}}},
+				})
+			})
+		})
+
+		Convey("When other fields are patched", func() {
+			err := ApplyPatches(edition, []Patch{{Op: "replace", Path: "/id", Value: "2022"}})
+
+			Convey("Then the nil slices and maps are unchanged", func() {
+				So(err, ShouldBeNil)
+				So(edition, ShouldResemble, &testEdition{ID: "2022"})
+				So(edition.Tags, ShouldBeNil)
+				So(edition.Links, ShouldBeNil)
+			})
+		})
+
+		Convey("Then a nil pointer field can be replaced", func() {
+			So(ApplyPatches(edition, []Patch{{Op: "replace", Path: "/alias", Value: "latest"}}), ShouldBeNil)
+			So(*edition.Alias, ShouldEqual, "latest")
+		})
+	})
+
+	Convey("Given a generic JSON document", t, func() {
+		doc := map[string]interface{}{"id": "cpih", "tags": []interface{}{"a"}}
+
+		Convey("Then patches are applied to the document", func() {
+			err := ApplyPatches(&doc, []Patch{{Op: "add", Path: "/tags/0", Value: "b"}})
+			So(err, ShouldBeNil)
+			So(doc, ShouldResemble, map[string]interface{}{"id": "cpih", "tags": []interface{}{"b", "a"}})
+		})
+	})
+
+	Convey("The document must be a non-nil pointer", t, func() {
+		So(ApplyPatches(testDataset{}, nil), ShouldEqual, ErrInvalidDocument)
+		So(ApplyPatches((*testDataset)(nil), nil), ShouldEqual, ErrInvalidDocument)
+	})
+}
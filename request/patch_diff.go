@@ -0,0 +1,221 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// DiffOptions is the configuration of DiffWithOptions
+type DiffOptions struct {
+	// ArrayKeys maps path patterns of arrays, as in PatchRules, to the name of a member that identifies each element
+	// of the array, e.g. {"/dimensions": "id"}. Elements of these arrays are matched by their key rather than their
+	// index, so that inserting or reordering elements generates 'add' and 'move' patches rather than replacing every
+	// following element. Arrays whose elements are not all objects with a unique key are compared by index.
+	ArrayKeys map[string]string
+}
+
+// Diff returns the patches that transform the before document into the after document, comparing arrays by index.
+// The documents can be any values that can be marshalled to JSON, including a json.RawMessage, and are compared by
+// their JSON representation. Applying the patches to before, with ApplyPatches, results in after. A patch that sets a
+// null value has a JSONNull Value, so that the patches are valid.
+func Diff(before, after interface{}) ([]Patch, error) {
+	return DiffWithOptions(before, after, DiffOptions{})
+}
+
+// DiffWithOptions returns the patches that transform the before document into the after document, as Diff, with
+// arrays compared according to the options
+func DiffWithOptions(before, after interface{}, opts DiffOptions) ([]Patch, error) {
+	b, err := normalise(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise before document: %w", err)
+	}
+	a, err := normalise(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise after document: %w", err)
+	}
+
+	d := &differ{patches: []Patch{}}
+	for pattern, key := range opts.ArrayKeys {
+		p, err := ParsePointer(pattern)
+		if err != nil {
+			return nil, err
+		}
+		d.arrayKeys = append(d.arrayKeys, arrayKey{pattern: p, key: key})
+	}
+
+	d.diff(JSONPointer{}, b, a)
+	return d.patches, nil
+}
+
+type arrayKey struct {
+	pattern JSONPointer
+	key     string
+}
+
+type differ struct {
+	arrayKeys []arrayKey
+	patches   []Patch
+}
+
+func (d *differ) diff(path JSONPointer, before, after interface{}) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			d.diffObject(path, b, a)
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			if key, ok := d.arrayKey(path, b, a); ok {
+				d.diffKeyedArray(path, key, b, a)
+			} else {
+				d.diffArray(path, b, a)
+			}
+			return
+		}
+	}
+
+	if !jsonEqual(before, after) {
+		d.add(OpReplace, path, after)
+	}
+}
+
+func (d *differ) add(op PatchOp, path JSONPointer, value interface{}) {
+	if value == nil {
+		value = JSONNull{}
+	}
+	d.patches = append(d.patches, Patch{Op: op.String(), Path: path.String(), Value: value})
+}
+
+func (d *differ) diffObject(path JSONPointer, before, after map[string]interface{}) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		memberPath := append(slices.Clip(path), k)
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inAfter:
+			d.patches = append(d.patches, Patch{Op: OpRemove.String(), Path: memberPath.String()})
+		case !inBefore:
+			d.add(OpAdd, memberPath, a)
+		default:
+			d.diff(memberPath, b, a)
+		}
+	}
+}
+
+// diffArray compares the arrays by index, after skipping the elements they have in common at their start and end,
+// so that inserting or removing elements does not replace every following element
+func (d *differ) diffArray(path JSONPointer, before, after []interface{}) {
+	start := 0
+	for start < len(before) && start < len(after) && jsonEqual(before[start], after[start]) {
+		start++
+	}
+	endBefore, endAfter := len(before), len(after)
+	for endBefore > start && endAfter > start && jsonEqual(before[endBefore-1], after[endAfter-1]) {
+		endBefore--
+		endAfter--
+	}
+
+	i := start
+	for ; i < endBefore && i < endAfter; i++ {
+		d.diff(elementPath(path, i), before[i], after[i])
+	}
+	for j := i; j < endAfter; j++ {
+		d.add(OpAdd, elementPath(path, j), after[j])
+	}
+	for j := i; j < endBefore; j++ {
+		// each removal shifts the following elements down to the same index
+		d.patches = append(d.patches, Patch{Op: OpRemove.String(), Path: elementPath(path, i).String()})
+	}
+}
+
+// diffKeyedArray compares the arrays by the key of each element, removing elements that are not in after, then moving
+// or adding each element of after into place
+func (d *differ) diffKeyedArray(path JSONPointer, key string, before, after []interface{}) {
+	afterKeys := map[string]bool{}
+	for _, elem := range after {
+		afterKeys[elementKey(elem, key)] = true
+	}
+
+	current := make([]interface{}, 0, len(before))
+	for i := len(before) - 1; i >= 0; i-- {
+		if !afterKeys[elementKey(before[i], key)] {
+			d.patches = append(d.patches, Patch{Op: OpRemove.String(), Path: elementPath(path, i).String()})
+		}
+	}
+	for _, elem := range before {
+		if afterKeys[elementKey(elem, key)] {
+			current = append(current, elem)
+		}
+	}
+
+	for i, elem := range after {
+		k := elementKey(elem, key)
+		j := slices.IndexFunc(current, func(c interface{}) bool { return elementKey(c, key) == k })
+		if j < 0 {
+			d.add(OpAdd, elementPath(path, i), elem)
+			current = slices.Insert(current, i, elem)
+			continue
+		}
+		if j != i {
+			d.patches = append(d.patches, Patch{Op: OpMove.String(), From: elementPath(path, j).String(), Path: elementPath(path, i).String()})
+			moved := current[j]
+			current = slices.Insert(slices.Delete(current, j, j+1), i, moved)
+		}
+		d.diff(elementPath(path, i), current[i], elem)
+	}
+}
+
+// arrayKey returns the key of the array at the path, if it matches one of the ArrayKeys patterns and every element of
+// both arrays is an object with a unique key
+func (d *differ) arrayKey(path JSONPointer, before, after []interface{}) (string, bool) {
+	for _, ak := range d.arrayKeys {
+		if patternMatches(ak.pattern, path) && uniquelyKeyed(before, ak.key) && uniquelyKeyed(after, ak.key) {
+			return ak.key, true
+		}
+	}
+	return "", false
+}
+
+func uniquelyKeyed(elems []interface{}, key string) bool {
+	seen := make(map[string]bool, len(elems))
+	for _, elem := range elems {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := obj[key]; !ok {
+			return false
+		}
+		k := elementKey(elem, key)
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+	}
+	return true
+}
+
+// elementKey returns the JSON representation of the element's key, so that keys of any type can be compared
+func elementKey(elem interface{}, key string) string {
+	obj, _ := elem.(map[string]interface{})
+	b, _ := json.Marshal(obj[key])
+	return string(b)
+}
+
+func elementPath(path JSONPointer, i int) JSONPointer {
+	return append(slices.Clip(path), strconv.Itoa(i))
+}
@@ -0,0 +1,194 @@
+package request
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// shouldRoundTrip asserts that applying the diff of before and after to before results in after
+func shouldRoundTrip(actual interface{}, expected ...interface{}) string {
+	before, after := []byte(actual.(string)), []byte(expected[0].(string))
+	opts := DiffOptions{}
+	if len(expected) > 1 {
+		opts = expected[1].(DiffOptions)
+	}
+
+	patches, err := DiffWithOptions(json.RawMessage(before), json.RawMessage(after), opts)
+	if err != nil {
+		return err.Error()
+	}
+	patched, err := ApplyPatchesJSON(before, patches)
+	if err != nil {
+		return err.Error()
+	}
+	if msg := ShouldBeTrue(jsonEqual(mustDecode(string(patched)), mustDecode(string(after)))); msg != "" {
+		return "patched document " + string(patched) + " does not equal " + string(after)
+	}
+	return ""
+}
+
+func TestDiff(t *testing.T) {
+	Convey("Equal documents have no differences", t, func() {
+		patches, err := Diff(json.RawMessage(`{"a":[1,{"b":2}]}`), json.RawMessage(`{"a":[1.0,{"b":2}]}`))
+		So(err, ShouldBeNil)
+		So(patches, ShouldBeEmpty)
+	})
+
+	Convey("Object members are added, removed and replaced, in order of their keys", t, func() {
+		patches, err := Diff(
+			json.RawMessage(`{"a":1,"b":{"c":"d","e":"f"},"g":[1]}`),
+			json.RawMessage(`{"a":2,"b":{"c":"d","x/y":true},"h":null}`),
+		)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "replace", Path: "/a", Value: json.Number("2")},
+			{Op: "remove", Path: "/b/e"},
+			{Op: "add", Path: "/b/x~1y", Value: true},
+			{Op: "remove", Path: "/g"},
+			{Op: "add", Path: "/h", Value: JSONNull{}},
+		})
+	})
+
+	Convey("A value of a different type is replaced", t, func() {
+		patches, err := Diff(json.RawMessage(`{"a":[1]}`), json.RawMessage(`{"a":{"0":1}}`))
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{{Op: "replace", Path: "/a", Value: map[string]interface{}{"0": json.Number("1")}}})
+	})
+
+	Convey("Arrays compared by index skip elements in common at their start and end", t, func() {
+		patches, err := Diff(json.RawMessage(`[1,2,3,4]`), json.RawMessage(`[1,5,6,3,4]`))
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "replace", Path: "/1", Value: json.Number("5")},
+			{Op: "add", Path: "/2", Value: json.Number("6")},
+		})
+
+		patches, err = Diff(json.RawMessage(`[1,2,3,4]`), json.RawMessage(`[1,4]`))
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "remove", Path: "/1"},
+			{Op: "remove", Path: "/1"},
+		})
+	})
+
+	Convey("Arrays compared by key generate add, remove and move patches", t, func() {
+		opts := DiffOptions{ArrayKeys: map[string]string{"/dimensions": "id"}}
+		patches, err := DiffWithOptions(
+			json.RawMessage(`{"dimensions":[{"id":"time"},{"id":"geography"},{"id":"aggregate","label":"A"}]}`),
+			json.RawMessage(`{"dimensions":[{"id":"aggregate","label":"B"},{"id":"sex"},{"id":"time"}]}`),
+			opts,
+		)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "remove", Path: "/dimensions/1"},
+			{Op: "move", From: "/dimensions/1", Path: "/dimensions/0"},
+			{Op: "replace", Path: "/dimensions/0/label", Value: "B"},
+			{Op: "add", Path: "/dimensions/1", Value: map[string]interface{}{"id": "sex"}},
+		})
+	})
+
+	Convey("Arrays without unique keys are compared by index", t, func() {
+		opts := DiffOptions{ArrayKeys: map[string]string{"/*": "id"}}
+		patches, err := DiffWithOptions(json.RawMessage(`{"a":[{"id":1},{"id":1}]}`), json.RawMessage(`{"a":[{"id":1}]}`), opts)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{{Op: "remove", Path: "/a/1"}})
+	})
+
+	Convey("Structs are compared by their JSON representation", t, func() {
+		patches, err := Diff(
+			testDataset{ID: "cpih", Version: 1},
+			testDataset{ID: "cpih", Version: 2, Title: "CPIH"},
+		)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "add", Path: "/title", Value: "CPIH"},
+			{Op: "replace", Path: "/version", Value: json.Number("2")},
+		})
+	})
+
+	Convey("Given patches that set null values", t, func() {
+		before := json.RawMessage(`{"a":1,"b":2}`)
+		patches, err := Diff(before, json.RawMessage(`{"a":null,"b":2,"c":null}`))
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{
+			{Op: "replace", Path: "/a", Value: JSONNull{}},
+			{Op: "add", Path: "/c", Value: JSONNull{}},
+		})
+
+		Convey("Then the patches are valid", func() {
+			for _, patch := range patches {
+				So(patch.Validate(allOps), ShouldBeNil)
+			}
+			rules := PatchRules{"/*": {Ops: []PatchOp{OpAdd, OpReplace}, Validators: []ValueValidator{OfType(TypeNull)}}}
+			So(rules.Validate(patches), ShouldBeNil)
+		})
+
+		Convey("Then the patches set the null values", func() {
+			patched, err := ApplyPatchesJSON(before, patches)
+			So(err, ShouldBeNil)
+			So(string(patched), ShouldEqual, `{"a":null,"b":2,"c":null}`)
+		})
+
+		Convey("Then the values are marshalled as null", func() {
+			b, err := json.Marshal(patches[0])
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `{"op":"replace","path":"/a","from":"","value":null}`)
+		})
+	})
+
+	Convey("An invalid array key pattern returns an error", t, func() {
+		_, err := DiffWithOptions(nil, nil, DiffOptions{ArrayKeys: map[string]string{"dimensions": "id"}})
+		So(err, ShouldWrap, ErrInvalidPointer)
+	})
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	keyed := DiffOptions{ArrayKeys: map[string]string{"/items": "id", "/items/*/tags": "name"}}
+
+	Convey("Applying the diff to the before document results in the after document", t, func() {
+		So(`{}`, shouldRoundTrip, `{"a":{"b":[1,2]}}`)
+		So(`{"a":{"b":[1,2]}}`, shouldRoundTrip, `{}`)
+		So(`[]`, shouldRoundTrip, `[1,2,3]`)
+		So(`[1,2,3]`, shouldRoundTrip, `[]`)
+		So(`[1,2,3]`, shouldRoundTrip, `[3,2,1]`)
+		So(`[1,[2,3]]`, shouldRoundTrip, `[1,[3]]`)
+		So(`"a"`, shouldRoundTrip, `{"a":1}`)
+		So(`{"a/b":{"~":1}}`, shouldRoundTrip, `{"a/b":{"~":2}}`)
+		So(`{"items":[{"id":1},{"id":2},{"id":3}]}`, shouldRoundTrip, `{"items":[{"id":3},{"id":4},{"id":1,"x":1}]}`, keyed)
+		So(`{"items":[{"id":1,"tags":[{"name":"a"},{"name":"b"}]}]}`, shouldRoundTrip, `{"items":[{"id":1,"tags":[{"name":"b","v":1},{"name":"a"}]}]}`, keyed)
+	})
+
+	Convey("Random documents round trip", t, func() {
+		rnd := rand.New(rand.NewPCG(1, 2))
+		for range 200 {
+			before, _ := json.Marshal(randomDocument(rnd, 3))
+			after, _ := json.Marshal(randomDocument(rnd, 3))
+			So(string(before), shouldRoundTrip, string(after))
+			So(string(before), shouldRoundTrip, string(after), keyed)
+		}
+	})
+}
+
+// randomDocument generates a random document with a small set of keys and values, so that documents overlap
+func randomDocument(rnd *rand.Rand, depth int) interface{} {
+	switch n := rnd.IntN(6); {
+	case depth == 0 || n < 2:
+		return rnd.IntN(3)
+	case n < 4:
+		obj := map[string]interface{}{}
+		for range rnd.IntN(4) {
+			obj[[]string{"id", "items", "tags", "name"}[rnd.IntN(4)]] = randomDocument(rnd, depth-1)
+		}
+		return obj
+	default:
+		arr := []interface{}{}
+		for i := range rnd.IntN(4) {
+			arr = append(arr, map[string]interface{}{"id": strconv.Itoa(rnd.IntN(4) + i), "v": randomDocument(rnd, depth-1)})
+		}
+		return arr
+	}
+}
@@ -0,0 +1,253 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ValueType is the type of a JSON value
+type ValueType string
+
+// Possible JSON value types
+const (
+	TypeString  ValueType = "string"
+	TypeNumber  ValueType = "number"
+	TypeBoolean ValueType = "boolean"
+	TypeObject  ValueType = "object"
+	TypeArray   ValueType = "array"
+	TypeNull    ValueType = "null"
+)
+
+// ValueValidator validates the value of an 'add', 'replace' or 'test' patch, returning an error describing why the
+// value is not valid. The value is in its generic JSON representation, as unmarshalled from the request body.
+type ValueValidator func(value interface{}) error
+
+// PatchRule is the ops permitted on a path, and the validators of the value of patches to the path
+type PatchRule struct {
+	Ops        []PatchOp
+	Validators []ValueValidator
+}
+
+// PatchRules maps path patterns to the rules of patches to matching paths. Patterns are JSON Pointers, in which a "*"
+// token matches any single token, e.g. "/dimensions/*/options". If several patterns match a path, the most specific
+// is used, i.e. the pattern whose first wildcard is latest. A patch to a path that matches no pattern is not permitted.
+// The 'from' path of a 'move' or 'copy' patch must also match a pattern permitting the op.
+type PatchRules map[string]PatchRule
+
+// PatchViolation is a reason why a patch is not permitted by the PatchRules
+type PatchViolation struct {
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (v PatchViolation) Error() string {
+	return fmt.Sprintf("patch %d (%s %s): %v", v.Index, v.Op, v.Path, v.Err)
+}
+
+// PatchViolationsError is returned when patches are not permitted by the PatchRules, with every violation
+type PatchViolationsError struct {
+	Violations []PatchViolation
+}
+
+func (e *PatchViolationsError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Error())
+	}
+	return "invalid patches: " + strings.Join(msgs, "; ")
+}
+
+// Errors describing a PatchViolation
+var (
+	ErrPathNotPermitted = errors.New("path not permitted")
+	ErrOpNotPermitted   = errors.New("op not permitted on path")
+)
+
+// GetPatchesWithRules gets the patches from the request body and returns it in the form of []Patch, in the same way
+// as GetPatches, but validates the patches against the rules. If any patches are not valid, a *PatchViolationsError
+// is returned with every violation, rather than just the first.
+func GetPatchesWithRules(requestBody io.ReadCloser, rules PatchRules) ([]Patch, error) {
+	patches, err := readPatches(requestBody)
+	if err != nil {
+		return []Patch{}, err
+	}
+
+	if err := rules.Validate(patches); err != nil {
+		return []Patch{}, err
+	}
+	return patches, nil
+}
+
+// Validate validates the patches against the rules, returning a *PatchViolationsError with every violation
+func (rules PatchRules) Validate(patches []Patch) error {
+	var violations []PatchViolation
+	for i, patch := range patches {
+		for _, err := range rules.validate(patch) {
+			violations = append(violations, PatchViolation{Index: i, Op: patch.Op, Path: patch.Path, Err: err})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PatchViolationsError{Violations: violations}
+	}
+	return nil
+}
+
+func (rules PatchRules) validate(patch Patch) []error {
+	if err := patch.Validate(allOps); err != nil {
+		return []error{err}
+	}
+
+	errs := rules.permit(patch.Op, patch.Path)
+	if patch.Op == OpMove.String() || patch.Op == OpCopy.String() {
+		for _, err := range rules.permit(patch.Op, patch.From) {
+			errs = append(errs, fmt.Errorf("from '%s': %w", patch.From, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if patch.Op == OpAdd.String() || patch.Op == OpReplace.String() || patch.Op == OpTest.String() {
+		rule, _ := rules.match(patch.Path)
+		for _, validator := range rule.Validators {
+			if err := validator(patch.Value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// permit returns the errors if the op is not permitted on the path
+func (rules PatchRules) permit(op, path string) []error {
+	rule, ok := rules.match(path)
+	if !ok {
+		return []error{ErrPathNotPermitted}
+	}
+	for _, permitted := range rule.Ops {
+		if permitted.String() == op {
+			return nil
+		}
+	}
+	return []error{ErrOpNotPermitted}
+}
+
+// match returns the rule of the most specific pattern that matches the path
+func (rules PatchRules) match(path string) (PatchRule, bool) {
+	tokens, err := ParsePointer(path)
+	if err != nil {
+		return PatchRule{}, false
+	}
+
+	var (
+		matched     PatchRule
+		found       bool
+		bestPattern JSONPointer
+	)
+	for pattern, rule := range rules {
+		patternTokens, err := ParsePointer(pattern)
+		if err != nil || !patternMatches(patternTokens, tokens) {
+			continue
+		}
+		if !found || moreSpecific(patternTokens, bestPattern) {
+			matched, found, bestPattern = rule, true, patternTokens
+		}
+	}
+	return matched, found
+}
+
+func patternMatches(pattern, path JSONPointer) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, token := range pattern {
+		if token != "*" && token != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// moreSpecific returns true if the first wildcard in pattern a is later than in pattern b. Patterns with the same
+// wildcards are ordered by their tokens, so that the choice is deterministic.
+func moreSpecific(a, b JSONPointer) bool {
+	for i := range a {
+		if aWild, bWild := a[i] == "*", b[i] == "*"; aWild != bWild {
+			return bWild
+		}
+	}
+	return slices.Compare(a, b) < 0
+}
+
+// OfType returns a ValueValidator that checks the value is one of the JSON types
+func OfType(types ...ValueType) ValueValidator {
+	return func(value interface{}) error {
+		t := valueType(value)
+		if !slices.Contains(types, t) {
+			return fmt.Errorf("value must be of type %v, not %s", types, t)
+		}
+		return nil
+	}
+}
+
+func valueType(value interface{}) ValueType {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case float64, json.Number:
+		return TypeNumber
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	default:
+		// values that were not unmarshalled from JSON are classified by their JSON representation
+		normalised, err := normalise(value)
+		if err != nil {
+			return ""
+		}
+		return valueType(normalised)
+	}
+}
+
+// OneOf returns a ValueValidator that checks the value equals one of the values, by their JSON representation
+func OneOf(values ...interface{}) ValueValidator {
+	return func(value interface{}) error {
+		v, err := normalise(value)
+		if err != nil {
+			return err
+		}
+		for _, allowed := range values {
+			if a, err := normalise(allowed); err == nil && jsonEqual(v, a) {
+				return nil
+			}
+		}
+		return fmt.Errorf("value must be one of %v", values)
+	}
+}
+
+// MatchesRegexp returns a ValueValidator that checks the value is a string matching the regular expression
+func MatchesRegexp(re *regexp.Regexp) ValueValidator {
+	return func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("value must be a string matching %s", re)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("value '%s' does not match %s", s, re)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testPatchRules = PatchRules{
+	"/state": {
+		Ops:        []PatchOp{OpReplace, OpTest},
+		Validators: []ValueValidator{OneOf("created", "published")},
+	},
+	"/title": {
+		Ops:        []PatchOp{OpAdd, OpReplace, OpRemove},
+		Validators: []ValueValidator{OfType(TypeString)},
+	},
+	"/dimensions/*/options": {
+		Ops:        []PatchOp{OpAdd, OpReplace},
+		Validators: []ValueValidator{OfType(TypeArray)},
+	},
+	"/dimensions/*/options/-": {
+		Ops:        []PatchOp{OpAdd},
+		Validators: []ValueValidator{OfType(TypeString), MatchesRegexp(regexp.MustCompile(`^\d{4}$`))},
+	},
+	"/dimensions/time/options": {
+		Ops: []PatchOp{OpRemove},
+	},
+	"/links/*": {
+		Ops: []PatchOp{OpMove, OpCopy},
+	},
+}
+
+func TestGetPatchesWithRules(t *testing.T) {
+	Convey("Given patches that are permitted by the rules", t, func() {
+		body := io.NopCloser(strings.NewReader(`[
+			{ "op": "test", "path": "/state", "value": "created" },
+			{ "op": "replace", "path": "/title", "value": "CPIH" },
+			{ "op": "add", "path": "/dimensions/geography/options", "value": [] },
+			{ "op": "add", "path": "/dimensions/time/options/-", "value": "2024" },
+			{ "op": "move", "from": "/links/old", "path": "/links/new" }
+		]`))
+
+		Convey("When GetPatchesWithRules is called", func() {
+			patches, err := GetPatchesWithRules(body, testPatchRules)
+
+			Convey("Then the patches are returned", func() {
+				So(err, ShouldBeNil)
+				So(patches, ShouldHaveLength, 5)
+			})
+		})
+	})
+
+	Convey("Given patches that violate the rules", t, func() {
+		body := io.NopCloser(strings.NewReader(`[
+			{ "op": "replace", "path": "/state", "value": "deleted" },
+			{ "op": "replace", "path": "/id", "value": "other" },
+			{ "op": "remove", "path": "/state" },
+			{ "op": "add", "path": "/dimensions/time/options/-", "value": 2024 },
+			{ "op": "copy", "from": "/id", "path": "/links/id" },
+			{ "op": "invalid", "path": "/title" },
+			{ "op": "add", "path": "/title", "value": "CPIH" }
+		]`))
+
+		Convey("When GetPatchesWithRules is called", func() {
+			patches, err := GetPatchesWithRules(body, testPatchRules)
+
+			Convey("Then every violation is returned", func() {
+				So(patches, ShouldBeEmpty)
+
+				var violationsErr *PatchViolationsError
+				So(errors.As(err, &violationsErr), ShouldBeTrue)
+
+				violations := violationsErr.Violations
+				So(violations, ShouldHaveLength, 7)
+				So(violations[0].Index, ShouldEqual, 0)
+				So(violations[0].Err.Error(), ShouldEqual, "value must be one of [created published]")
+				So(violations[1].Index, ShouldEqual, 1)
+				So(violations[1].Err, ShouldEqual, ErrPathNotPermitted)
+				So(violations[2].Index, ShouldEqual, 2)
+				So(violations[2].Err, ShouldEqual, ErrOpNotPermitted)
+				So(violations[3].Index, ShouldEqual, 3)
+				So(violations[3].Err.Error(), ShouldEqual, "value must be of type [string], not number")
+				So(violations[4].Index, ShouldEqual, 3)
+				So(violations[4].Err.Error(), ShouldEqual, `value must be a string matching ^\d{4}$`)
+				So(violations[5].Index, ShouldEqual, 4)
+				So(violations[5].Err, ShouldWrap, ErrPathNotPermitted)
+				So(violations[6].Index, ShouldEqual, 5)
+				So(violations[6].Op, ShouldEqual, "invalid")
+
+				So(err.Error(), ShouldStartWith, "invalid patches: patch 0 (replace /state): value must be one of")
+			})
+		})
+	})
+
+	Convey("Given an empty request body", t, func() {
+		_, err := GetPatchesWithRules(io.NopCloser(strings.NewReader("")), testPatchRules)
+
+		Convey("Then the same error as GetPatches is returned", func() {
+			So(err, ShouldResemble, fmt.Errorf("empty request body given"))
+		})
+	})
+}
+
+func TestPatchRulesMatch(t *testing.T) {
+	Convey("The most specific matching pattern is used", t, func() {
+		rule, ok := testPatchRules.match("/dimensions/time/options")
+		So(ok, ShouldBeTrue)
+		So(rule.Ops, ShouldResemble, []PatchOp{OpRemove})
+
+		rule, ok = testPatchRules.match("/dimensions/geography/options")
+		So(ok, ShouldBeTrue)
+		So(rule.Ops, ShouldResemble, []PatchOp{OpAdd, OpReplace})
+	})
+
+	Convey("Escaped tokens are matched", t, func() {
+		rules := PatchRules{"/links/a~1b": {Ops: []PatchOp{OpRemove}}}
+		So(rules.Validate([]Patch{{Op: "remove", Path: "/links/a~1b"}}), ShouldBeNil)
+	})
+
+	Convey("Paths with a different number of tokens are not matched", t, func() {
+		_, ok := testPatchRules.match("/dimensions/time")
+		So(ok, ShouldBeFalse)
+		_, ok = testPatchRules.match("/title/extra")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestValueValidators(t *testing.T) {
+	Convey("OfType checks the JSON type of the value", t, func() {
+		So(OfType(TypeNumber)(float64(1)), ShouldBeNil)
+		So(OfType(TypeNumber)(3), ShouldBeNil)
+		So(OfType(TypeObject)(map[string]interface{}{}), ShouldBeNil)
+		So(OfType(TypeObject)(struct{}{}), ShouldBeNil)
+		So(OfType(TypeString, TypeNull)(nil), ShouldBeNil)
+		So(OfType(TypeBoolean)("true"), ShouldNotBeNil)
+	})
+
+	Convey("OneOf compares values by their JSON representation", t, func() {
+		So(OneOf(1, 2)(float64(2)), ShouldBeNil)
+		So(OneOf([]string{"a"})([]interface{}{"a"}), ShouldBeNil)
+		So(OneOf(1, 2)(float64(3)), ShouldNotBeNil)
+	})
+
+	Convey("MatchesRegexp only accepts matching strings", t, func() {
+		re := regexp.MustCompile(`^[a-z]+$`)
+		So(MatchesRegexp(re)("abc"), ShouldBeNil)
+		So(MatchesRegexp(re)("ABC"), ShouldNotBeNil)
+		So(MatchesRegexp(re)(1), ShouldNotBeNil)
+	})
+
+	Convey("A custom validator is a function", t, func() {
+		rules := PatchRules{"/count": {
+			Ops: []PatchOp{OpReplace},
+			Validators: []ValueValidator{func(value interface{}) error {
+				if n, ok := value.(float64); !ok || n < 0 {
+					return errors.New("count must not be negative")
+				}
+				return nil
+			}},
+		}}
+		err := rules.Validate([]Patch{{Op: "replace", Path: "/count", Value: float64(-1)}})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "invalid patches: patch 0 (replace /count): count must not be negative")
+	})
+}
@@ -1,7 +1,9 @@
 package request
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -263,3 +265,37 @@ func TestValidate(t *testing.T) {
 		So(patch.Validate(supportedOps), ShouldResemble, ErrMissingMember([]string{"path"}))
 	})
 }
+
+func TestGetPatchesNullValue(t *testing.T) {
+	supportedOps := []PatchOp{OpAdd, OpReplace, OpTest}
+
+	Convey("A patch with an explicit null value is valid and has a JSONNull value", t, func() {
+		patches, err := GetPatches(io.NopCloser(strings.NewReader(`[{ "op": "replace", "path": "/a", "value": null }]`)), supportedOps)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{{Op: "replace", Path: "/a", Value: JSONNull{}}})
+
+		rules := PatchRules{"/a": {Ops: []PatchOp{OpReplace}, Validators: []ValueValidator{OfType(TypeNull)}}}
+		patches, err = GetPatchesWithRules(io.NopCloser(strings.NewReader(`[{ "op": "replace", "path": "/a", "value": null }]`)), rules)
+		So(err, ShouldBeNil)
+		So(patches, ShouldResemble, []Patch{{Op: "replace", Path: "/a", Value: JSONNull{}}})
+	})
+
+	Convey("A patch without a value is not valid", t, func() {
+		_, err := GetPatches(io.NopCloser(strings.NewReader(`[{ "op": "replace", "path": "/a" }]`)), supportedOps)
+		So(err, ShouldResemble, ErrMissingMember([]string{"value"}))
+	})
+
+	Convey("Patches round trip through JSON", t, func() {
+		patches := []Patch{
+			{Op: "replace", Path: "/a", Value: JSONNull{}},
+			{Op: "add", Path: "/b", Value: map[string]interface{}{"c": []interface{}{1.0, "d"}}},
+			{Op: "move", From: "/e", Path: "/f"},
+		}
+		b, err := json.Marshal(patches)
+		So(err, ShouldBeNil)
+
+		var received []Patch
+		So(json.Unmarshal(b, &received), ShouldBeNil)
+		So(received, ShouldResemble, patches)
+	})
+}
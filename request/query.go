@@ -0,0 +1,324 @@
+package request
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBindTarget is returned by BindQuery when the destination is not a non-nil pointer to a struct
+var ErrInvalidBindTarget = errors.New("query parameters can only be bound to a non-nil pointer to a struct")
+
+// FieldError is an invalid query parameter
+type FieldError struct {
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("invalid query parameter '%s': %v", e.Param, e.Err)
+	}
+	return fmt.Sprintf("invalid query parameter '%s' value '%s': %v", e.Param, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the status code of the response to a request with an invalid query parameter
+func (e *FieldError) Code() int {
+	return http.StatusBadRequest
+}
+
+// LogData returns the log data of the error
+func (e *FieldError) LogData() map[string]interface{} {
+	return map[string]interface{}{"param": e.Param, "value": e.Value}
+}
+
+// FieldErrors are every invalid query parameter found by BindQuery
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Code returns the status code of the response to a request with invalid query parameters
+func (e FieldErrors) Code() int {
+	return http.StatusBadRequest
+}
+
+// Errors returns the errors as a []error, to be passed to responder.Errors
+func (e FieldErrors) Errors() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindQuery decodes the query parameters into the fields of the struct that dst points to. Each field is bound to the
+// parameter named by its `query` tag, and fields without a tag are ignored, other than embedded structs, whose fields
+// are bound in turn. The tag may be followed by options:
+//
+//   - required: the parameter must be given
+//   - csv: each value is split on commas, so that a slice can be given as "a,b" as well as by repeating the parameter
+//
+// Fields may be strings, bools, ints, uints, floats, time.Time (parsed with the layout in the `layout` tag, or
+// RFC 3339), time.Duration, types implementing encoding.TextUnmarshaler, and slices of or pointers to any of these.
+// A scalar field is bound to the first value of its parameter, and empty values are ignored. Further tags set the
+// `default` value of a field when its parameter is not given, the `min` and `max` of numbers, and the values of an
+// `enum`, separated by commas. Bounds and enums apply to each element of a slice.
+//
+// For example:
+//
+//	type ListParams struct {
+//		Limit  int      `query:"limit" default:"20" min:"0" max:"1000"`
+//		Offset int      `query:"offset" min:"0"`
+//		Sort   string   `query:"sort" enum:"title,-title"`
+//		IDs    []string `query:"id,csv"`
+//	}
+//
+// If any parameters are not valid, FieldErrors is returned with every invalid parameter.
+func BindQuery(values url.Values, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBindTarget
+	}
+
+	var errs FieldErrors
+	if err := bindStruct(values, v.Elem(), &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// BindQueryRequest decodes the query parameters of the request into dst, see BindQuery
+func BindQueryRequest(req *http.Request, dst interface{}) error {
+	return BindQuery(req.URL.Query(), dst)
+}
+
+func bindStruct(values url.Values, v reflect.Value, errs *FieldErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("query")
+		if !hasTag {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := bindStruct(values, v.Field(i), errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		options := strings.Split(opts, ",")
+		b := fieldBinder{
+			param:    name,
+			field:    field,
+			required: slices.Contains(options, "required"),
+			csv:      slices.Contains(options, "csv"),
+		}
+		if err := b.checkType(); err != nil {
+			return err
+		}
+		if err := b.bind(values[name], v.Field(i)); err != nil {
+			*errs = append(*errs, err)
+		}
+	}
+	return nil
+}
+
+type fieldBinder struct {
+	param    string
+	field    reflect.StructField
+	required bool
+	csv      bool
+}
+
+// checkType returns an error if the field cannot be bound, which is a programming error rather than a FieldError
+func (b fieldBinder) checkType() error {
+	t := b.field.Type
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) || t == timeType {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	}
+	return fmt.Errorf("cannot bind query parameter '%s' to field %s of type %s", b.param, b.field.Name, b.field.Type)
+}
+
+func (b fieldBinder) bind(raw []string, v reflect.Value) *FieldError {
+	var given []string
+	for _, value := range raw {
+		if b.csv {
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					given = append(given, s)
+				}
+			}
+		} else if value != "" {
+			given = append(given, value)
+		}
+	}
+
+	if len(given) == 0 {
+		if b.required {
+			return &FieldError{Param: b.param, Err: errors.New("required")}
+		}
+		def, ok := b.field.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		given = []string{def}
+		if v.Kind() == reflect.Slice {
+			given = strings.Split(def, ",")
+		}
+	}
+
+	if v.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(v.Type(), len(given), len(given))
+		for i, s := range given {
+			if err := b.bindValue(s, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return b.bindValue(given[0], v)
+}
+
+func (b fieldBinder) bindValue(s string, v reflect.Value) *FieldError {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := b.bindValue(s, ptr.Elem()); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	fieldErr := func(err error) *FieldError {
+		return &FieldError{Param: b.param, Value: s, Err: err}
+	}
+
+	if enum, ok := b.field.Tag.Lookup("enum"); ok && !slices.Contains(strings.Split(enum, ","), s) {
+		return fieldErr(fmt.Errorf("must be one of [%s]", strings.ReplaceAll(enum, ",", ", ")))
+	}
+
+	switch {
+	case v.Type() == timeType:
+		layout := b.field.Tag.Get("layout")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return fieldErr(fmt.Errorf("must be a time in the format %s", layout))
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fieldErr(errors.New("must be a duration, e.g. 1h30m"))
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fieldErr(err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(s)
+		if err != nil {
+			return fieldErr(errors.New("must be true or false"))
+		}
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fieldErr(errors.New("must be an integer"))
+		}
+		if err := b.checkBounds(float64(parsed)); err != nil {
+			return fieldErr(err)
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fieldErr(errors.New("must be a non-negative integer"))
+		}
+		if err := b.checkBounds(float64(parsed)); err != nil {
+			return fieldErr(err)
+		}
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fieldErr(errors.New("must be a number"))
+		}
+		if err := b.checkBounds(parsed); err != nil {
+			return fieldErr(err)
+		}
+		v.SetFloat(parsed)
+	}
+	return nil
+}
+
+func (b fieldBinder) checkBounds(n float64) error {
+	if minTag, ok := b.field.Tag.Lookup("min"); ok {
+		if bound, err := strconv.ParseFloat(minTag, 64); err == nil && n < bound {
+			return fmt.Errorf("must be at least %s", minTag)
+		}
+	}
+	if maxTag, ok := b.field.Tag.Lookup("max"); ok {
+		if bound, err := strconv.ParseFloat(maxTag, 64); err == nil && n > bound {
+			return fmt.Errorf("must be at most %s", maxTag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package request_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-net/v3/responder"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBindQueryErrorsResponse(t *testing.T) {
+	Convey("Given invalid query parameters", t, func() {
+		var params struct {
+			Limit int    `query:"limit" max:"1000"`
+			Sort  string `query:"sort" enum:"title,-title"`
+		}
+		err := request.BindQuery(url.Values{"limit": {"1001"}, "sort": {"name"}}, &params)
+		So(err, ShouldHaveSameTypeAs, request.FieldErrors{})
+
+		Convey("When the errors are rendered by responder.Errors", func() {
+			w := httptest.NewRecorder()
+			responder.New().Errors(context.Background(), w, http.StatusBadRequest, err.(request.FieldErrors).Errors())
+
+			Convey("Then every invalid parameter is in the response", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				var body struct {
+					Errors []string `json:"errors"`
+				}
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Errors, ShouldResemble, []string{
+					"invalid query parameter 'limit' value '1001': must be at most 1000",
+					"invalid query parameter 'sort' value 'name': must be one of [title, -title]",
+				})
+			})
+		})
+	})
+}
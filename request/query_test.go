@@ -0,0 +1,159 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testPagination struct {
+	Limit  int `query:"limit" default:"20" min:"1" max:"1000"`
+	Offset int `query:"offset" min:"0"`
+}
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return json.Unmarshal(text, (*int)(l))
+	}
+	return nil
+}
+
+type testListParams struct {
+	testPagination
+	Sort       string        `query:"sort" default:"title" enum:"title,-title,release_date"`
+	IDs        []string      `query:"id"`
+	Dimensions []string      `query:"dimensions,csv"`
+	Years      []int         `query:"year,csv" min:"1900"`
+	Published  *bool         `query:"published"`
+	Since      time.Time     `query:"since"`
+	Day        time.Time     `query:"day" layout:"2006-01-02"`
+	Timeout    time.Duration `query:"timeout"`
+	Score      float64       `query:"score" max:"1"`
+	Level      testLevel     `query:"level"`
+	Dataset    string        `query:"dataset,required"`
+	Ignored    string
+	Skipped    string `query:"-"`
+}
+
+func TestBindQuery(t *testing.T) {
+	Convey("Given valid query parameters", t, func() {
+		values, _ := url.ParseQuery("limit=50&offset=100&sort=-title&id=a&id=b&dimensions=geography,%20time&dimensions=sex" +
+			"&year=2020,2021&published=true&since=2024-01-02T03:04:05Z&day=2024-02-03&timeout=1m30s&score=0.5&level=high" +
+			"&dataset=cpih&Ignored=x&-=y")
+
+		Convey("When they are bound to a struct", func() {
+			var params testListParams
+			err := BindQuery(values, &params)
+
+			Convey("Then each field is set from its parameter", func() {
+				So(err, ShouldBeNil)
+				published := true
+				So(params, ShouldResemble, testListParams{
+					testPagination: testPagination{Limit: 50, Offset: 100},
+					Sort:           "-title",
+					IDs:            []string{"a", "b"},
+					Dimensions:     []string{"geography", "time", "sex"},
+					Years:          []int{2020, 2021},
+					Published:      &published,
+					Since:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					Day:            time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC),
+					Timeout:        90 * time.Second,
+					Score:          0.5,
+					Level:          2,
+					Dataset:        "cpih",
+				})
+			})
+		})
+	})
+
+	Convey("Given only the required query parameters", t, func() {
+		values := url.Values{"dataset": {"cpih"}, "limit": {""}}
+
+		Convey("When they are bound to a struct", func() {
+			var params testListParams
+			err := BindQuery(values, &params)
+
+			Convey("Then the defaults are set, and other fields are not", func() {
+				So(err, ShouldBeNil)
+				So(params, ShouldResemble, testListParams{
+					testPagination: testPagination{Limit: 20},
+					Sort:           "title",
+					Dataset:        "cpih",
+				})
+			})
+		})
+	})
+
+	Convey("Given invalid query parameters", t, func() {
+		values, _ := url.ParseQuery("limit=1001&offset=x&sort=name&year=2020,1800&published=maybe&since=yesterday" +
+			"&timeout=5&score=2&level=medium")
+
+		Convey("When they are bound to a struct", func() {
+			var params testListParams
+			err := BindQuery(values, &params)
+
+			Convey("Then every invalid parameter is returned", func() {
+				var errs FieldErrors
+				So(err, ShouldHaveSameTypeAs, errs)
+				errs = err.(FieldErrors)
+
+				messages := make([]string, 0, len(errs))
+				for _, fieldErr := range errs {
+					messages = append(messages, fieldErr.Error())
+				}
+				So(messages, ShouldResemble, []string{
+					"invalid query parameter 'limit' value '1001': must be at most 1000",
+					"invalid query parameter 'offset' value 'x': must be an integer",
+					"invalid query parameter 'sort' value 'name': must be one of [title, -title, release_date]",
+					"invalid query parameter 'year' value '1800': must be at least 1900",
+					"invalid query parameter 'published' value 'maybe': must be true or false",
+					"invalid query parameter 'since' value 'yesterday': must be a time in the format 2006-01-02T15:04:05Z07:00",
+					"invalid query parameter 'timeout' value '5': must be a duration, e.g. 1h30m",
+					"invalid query parameter 'score' value '2': must be at most 1",
+					"invalid query parameter 'level' value 'medium': invalid character 'm' looking for beginning of value",
+					"invalid query parameter 'dataset': required",
+				})
+				So(errs.Code(), ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+
+	Convey("Given a struct with a field of an unsupported type", t, func() {
+		var params struct {
+			Filter map[string]string `query:"filter"`
+		}
+
+		Convey("Then BindQuery returns an error", func() {
+			err := BindQuery(url.Values{}, &params)
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotHaveSameTypeAs, FieldErrors{})
+		})
+	})
+
+	Convey("The destination must be a non-nil pointer to a struct", t, func() {
+		var params testListParams
+		So(BindQuery(url.Values{}, params), ShouldEqual, ErrInvalidBindTarget)
+		So(BindQuery(url.Values{}, (*testListParams)(nil)), ShouldEqual, ErrInvalidBindTarget)
+		limit := 0
+		So(BindQuery(url.Values{}, &limit), ShouldEqual, ErrInvalidBindTarget)
+	})
+
+	Convey("BindQueryRequest binds the query parameters of a request", t, func() {
+		var params testPagination
+		req := httptest.NewRequest(http.MethodGet, "/datasets?limit=5", http.NoBody)
+		So(BindQueryRequest(req, &params), ShouldBeNil)
+		So(params.Limit, ShouldEqual, 5)
+	})
+}
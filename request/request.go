@@ -17,13 +17,12 @@ const (
 
 // GetRequestId gets the correlation id on the context
 func GetRequestId(ctx context.Context) string {
-	correlationID, _ := ctx.Value(RequestIdKey).(string)
-	return correlationID
+	return GetInfo(ctx).RequestID
 }
 
 // WithRequestId sets the correlation id on the context
 func WithRequestId(ctx context.Context, correlationID string) context.Context {
-	return context.WithValue(ctx, RequestIdKey, correlationID)
+	return withInfoValue(ctx, RequestIdKey, correlationID)
 }
 
 // AddRequestIdHeader add header for given correlation ID